@@ -0,0 +1,139 @@
+package tidy
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// AccessFinding is one problem libtidy's accessibility checks found.
+type AccessFinding struct {
+	Check      string      // libtidy's check ID, e.g. "1.1.1.1".
+	Checkpoint string      // The WCAG 1.0 checkpoint checked, e.g. "1.1".
+	Priority   AccessLevel // The checkpoint's WCAG 1.0 priority.
+	Criterion  string      // The nearest WCAG 2 success criterion, or "".
+	Code       MessageCode
+	Line       int
+	Column     int
+	Message    string
+}
+
+// AccessibilityReport holds the findings of an accessibility audit.
+type AccessibilityReport struct {
+	Level    AccessLevel // The highest priority checked.
+	Findings []AccessFinding
+}
+
+// wcagCheckpoints gives the WCAG 1.0 priority of each checkpoint and the WCAG
+// 2 success criterion that covers most of the same ground, following the
+// W3C's comparison of the two. Checkpoints WCAG 2 dropped have none.
+var wcagCheckpoints = map[string]struct {
+	priority  AccessLevel
+	criterion string
+}{
+	"1.1": {Priority1Checks, "1.1.1"}, "1.2": {Priority1Checks, "1.1.1"}, "1.3": {Priority1Checks, "1.2.3"},
+	"1.4": {Priority1Checks, "1.2.2"}, "1.5": {Priority3Checks, "1.1.1"},
+	"2.1": {Priority1Checks, "1.4.1"}, "2.2": {Priority2Checks, "1.4.3"},
+	"3.1": {Priority2Checks, "1.1.1"}, "3.2": {Priority2Checks, "4.1.1"}, "3.3": {Priority2Checks, "1.3.1"},
+	"3.4": {Priority2Checks, "1.4.4"}, "3.5": {Priority2Checks, "1.3.1"}, "3.6": {Priority2Checks, "1.3.1"},
+	"3.7": {Priority2Checks, "1.3.1"},
+	"4.1": {Priority1Checks, "3.1.2"}, "4.2": {Priority3Checks, ""}, "4.3": {Priority3Checks, "3.1.1"},
+	"5.1": {Priority1Checks, "1.3.1"}, "5.2": {Priority1Checks, "1.3.1"}, "5.3": {Priority2Checks, "1.3.2"},
+	"5.4": {Priority2Checks, "1.3.1"}, "5.5": {Priority3Checks, "1.3.1"}, "5.6": {Priority3Checks, "1.3.1"},
+	"6.1": {Priority1Checks, "1.3.2"}, "6.2": {Priority1Checks, "1.1.1"}, "6.3": {Priority1Checks, "4.1.2"},
+	"6.4": {Priority2Checks, "2.1.1"}, "6.5": {Priority2Checks, "4.1.2"},
+	"7.1": {Priority1Checks, "2.3.1"}, "7.2": {Priority2Checks, "2.2.2"}, "7.3": {Priority2Checks, "2.2.2"},
+	"7.4": {Priority2Checks, "2.2.1"}, "7.5": {Priority2Checks, "3.2.5"},
+	"8.1": {Priority1Checks, "4.1.2"},
+	"9.1": {Priority1Checks, "1.1.1"}, "9.2": {Priority2Checks, "2.1.1"}, "9.3": {Priority2Checks, "2.1.1"},
+	"9.4": {Priority3Checks, "2.4.3"}, "9.5": {Priority3Checks, "2.1.1"},
+	"10.1": {Priority2Checks, "3.2.5"}, "10.2": {Priority2Checks, "1.3.1"}, "10.3": {Priority3Checks, "1.3.2"},
+	"10.4": {Priority3Checks, ""}, "10.5": {Priority3Checks, ""},
+	"11.1": {Priority2Checks, "4.1.1"}, "11.2": {Priority2Checks, ""}, "11.3": {Priority3Checks, ""},
+	"11.4": {Priority1Checks, ""},
+	"12.1": {Priority1Checks, "2.4.1"}, "12.2": {Priority2Checks, "4.1.2"}, "12.3": {Priority2Checks, "1.3.1"},
+	"12.4": {Priority2Checks, "3.3.2"},
+	"13.1": {Priority2Checks, "2.4.4"}, "13.2": {Priority2Checks, ""}, "13.3": {Priority2Checks, ""},
+	"13.4": {Priority2Checks, "3.2.3"}, "13.5": {Priority3Checks, "2.4.1"}, "13.6": {Priority3Checks, "2.4.1"},
+	"13.7": {Priority3Checks, ""}, "13.8": {Priority3Checks, "2.4.6"}, "13.9": {Priority3Checks, ""},
+	"13.10": {Priority3Checks, "2.4.1"},
+	"14.1":  {Priority1Checks, "3.1.5"}, "14.2": {Priority3Checks, ""}, "14.3": {Priority3Checks, "3.2.3"},
+}
+
+// libtidy starts each accessibility message with its check ID in brackets.
+var accessCheck = regexp.MustCompile(`^\[(\d+\.\d+)((?:\.\d+)*)\]:?\s*`)
+
+// newAccessibilityReport picks the accessibility findings out of diags.
+func newAccessibilityReport(level AccessLevel, diags []Diagnostic) *AccessibilityReport {
+	r := &AccessibilityReport{Level: level}
+	for _, d := range diags {
+		if d.Severity != Access {
+			continue
+		}
+		f := AccessFinding{Code: d.Code, Line: d.Line, Column: d.Column, Message: d.Message}
+		if m := accessCheck.FindStringSubmatch(d.Message); m != nil {
+			f.Check = m[1] + m[2]
+			f.Checkpoint = m[1]
+			f.Message = d.Message[len(m[0]):]
+			cp := wcagCheckpoints[f.Checkpoint]
+			f.Priority, f.Criterion = cp.priority, cp.criterion
+		}
+		r.Findings = append(r.Findings, f)
+	}
+	return r
+}
+
+// ByPriority returns the findings for checkpoints of the given priority.
+func (r *AccessibilityReport) ByPriority(p AccessLevel) []AccessFinding {
+	var found []AccessFinding
+	for _, f := range r.Findings {
+		if f.Priority == p {
+			found = append(found, f)
+		}
+	}
+	return found
+}
+
+// ByCheck groups the findings by check ID.
+func (r *AccessibilityReport) ByCheck() map[string][]AccessFinding {
+	checks := make(map[string][]AccessFinding)
+	for _, f := range r.Findings {
+		checks[f.Check] = append(checks[f.Check], f)
+	}
+	return checks
+}
+
+// Checks returns the IDs of the checks that failed, in checkpoint order.
+func (r *AccessibilityReport) Checks() []string {
+	var ids []string
+	for id := range r.ByCheck() {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return checkLess(ids[i], ids[j]) })
+	return ids
+}
+
+// Passed says whether the document had no findings at priority p. A priority
+// above the level audited has not been checked, so it never passes. WCAG 1.0
+// conformance level "AA", for instance, needs Passed for priorities 1 and 2.
+func (r *AccessibilityReport) Passed(p AccessLevel) bool {
+	if p < Priority1Checks || p > r.Level {
+		return false
+	}
+	return len(r.ByPriority(p)) == 0
+}
+
+// checkLess orders dotted check IDs numerically, so that 2.1 sorts before
+// 10.1.
+func checkLess(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if len(as[i]) != len(bs[i]) {
+			return len(as[i]) < len(bs[i])
+		}
+		if as[i] != bs[i] {
+			return as[i] < bs[i]
+		}
+	}
+	return len(as) < len(bs)
+}
@@ -0,0 +1,44 @@
+package tidy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_AccessibilityReport(t *testing.T) {
+	diags := []Diagnostic{
+		{Severity: Warning, Line: 1, Column: 1, Message: "missing <!DOCTYPE> declaration"},
+		{Severity: Access, Line: 4, Column: 1, Message: "[1.1.1.1]: <img> missing 'alt' text."},
+		{Severity: Access, Line: 9, Column: 3, Message: "[13.1.1.1]: link text not meaningful."},
+		{Severity: Access, Line: 12, Column: 3, Message: "[1.1.1.1]: <img> missing 'alt' text."},
+		{Severity: Access, Line: 1, Column: 1, Message: "[2.1.1.5]: ensure information not conveyed through color alone (image)."},
+	}
+	r := newAccessibilityReport(Priority2Checks, diags)
+
+	if len(r.Findings) != 4 {
+		t.Fatalf("Got %d findings; want the 4 accessibility messages", len(r.Findings))
+	}
+	f := r.Findings[0]
+	if f.Check != "1.1.1.1" || f.Checkpoint != "1.1" || f.Priority != Priority1Checks || f.Criterion != "1.1.1" {
+		t.Errorf("Finding classified as %+v", f)
+	}
+	if f.Message != "<img> missing 'alt' text." || f.Line != 4 {
+		t.Errorf("Finding kept as %+v", f)
+	}
+
+	if got := len(r.ByCheck()["1.1.1.1"]); got != 2 {
+		t.Errorf("Got %d findings for check 1.1.1.1; want 2", got)
+	}
+	if got, want := r.Checks(), []string{"1.1.1.1", "2.1.1.5", "13.1.1.1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Checks() = %v; want %v", got, want)
+	}
+	if len(r.ByPriority(Priority2Checks)) != 1 {
+		t.Errorf("Checkpoint 13.1 is priority 2")
+	}
+	if r.Passed(Priority1Checks) || r.Passed(Priority2Checks) || r.Passed(Priority3Checks) {
+		t.Errorf("Priorities with findings, or not audited, must not pass")
+	}
+	if !newAccessibilityReport(Priority3Checks, diags[:1]).Passed(Priority3Checks) {
+		t.Errorf("A priority without findings must pass")
+	}
+}
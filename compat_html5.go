@@ -0,0 +1,11 @@
+//go:build tidyhtml5
+// +build tidyhtml5
+
+package tidy
+
+// Building with the tidyhtml5 tag selects the tidy-html5 headers.
+
+/*
+#cgo CFLAGS: -DGOTIDY_HTML5
+*/
+import "C"
@@ -0,0 +1,64 @@
+package tidy
+
+/*
+#cgo CFLAGS: -I/usr/include/tidy
+#cgo LDFLAGS: -ltidy -L/usr/local/lib
+#include "tidy_compat.h"
+*/
+import "C"
+import (
+	"errors"
+)
+
+// Snapshot records the current value of every option so that it can later be
+// rolled back with Restore. Only one snapshot is kept per instance; taking a
+// new one replaces the previous.
+func (this *Tidy) Snapshot() (bool, error) {
+	if C.tidyOptSnapshot(this.tdoc) == 0 {
+		return false, errors.New("Unable to take a snapshot of the current options")
+	}
+	return true, nil
+}
+
+// Restore resets every option to the value it had when Snapshot was last
+// called.
+func (this *Tidy) Restore() (bool, error) {
+	if C.tidyOptResetToSnapshot(this.tdoc) == 0 {
+		return false, errors.New("Unable to restore options from snapshot")
+	}
+	return true, nil
+}
+
+// Reset sets every option back to its libtidy default.
+func (this *Tidy) Reset() (bool, error) {
+	if C.tidyOptResetAllToDefault(this.tdoc) == 0 {
+		return false, errors.New("Unable to reset options to their defaults")
+	}
+	return true, nil
+}
+
+// Clone returns a new instance configured with a copy of this instance's
+// options. The clone is independent: changing its options does not affect
+// the original, which makes it suitable for handing to another goroutine.
+// The caller must Free the clone when done with it.
+func (this *Tidy) Clone() *Tidy {
+	t, _ := New()
+	C.tidyOptCopyConfig(t.tdoc, this.tdoc)
+	t.extra = this.extra
+	return t
+}
+
+// saveConfig copies the current options aside so that a call can change them
+// and put them back with restoreConfig. Unlike Snapshot, it leaves the
+// caller's snapshot alone.
+func (this *Tidy) saveConfig() C.TidyDoc {
+	saved := C.tidyCreate()
+	C.tidyOptCopyConfig(saved, this.tdoc)
+	return saved
+}
+
+// restoreConfig puts back options copied aside by saveConfig.
+func (this *Tidy) restoreConfig(saved C.TidyDoc) {
+	C.tidyOptCopyConfig(this.tdoc, saved)
+	C.tidyRelease(saved)
+}
@@ -0,0 +1,39 @@
+package tidy
+
+/*
+#cgo CFLAGS: -I/usr/include/tidy
+#cgo LDFLAGS: -ltidy -L/usr/local/lib
+#include "tidy_compat.h"
+*/
+import "C"
+import (
+	"strings"
+)
+
+// Diff tidies input and returns a unified diff from the input to the output,
+// to show what the current options would change. Overrides and the error are
+// as for Tidy. With StructuralDiff on, the diff compares the documents'
+// elements and text rather than their lines.
+func (this *Tidy) Diff(input []byte, overrides ...Option) (string, error) {
+	res, err := this.TidyBytes(input, overrides...)
+	if res == nil {
+		return "", err
+	}
+
+	from := string(input)
+	if c := decoderFor(Encoding(this.optGetInt(C.TidyInCharEncoding))); c != nil {
+		from, _ = c.Decode(input)
+	}
+	from = strings.TrimPrefix(from, "\uFEFF")
+	to := res.String()
+	if this.extra.structuralDiff {
+		from, to = structure(from), structure(to)
+	}
+	return UnifiedDiff("input", from, "tidied", to), err
+}
+
+// This option specifies if Diff should compare the documents as trees of elements, attributes and text, one to a line and indented by depth, so that changes to whitespace and line breaks alone don't show.
+func (this *Tidy) StructuralDiff(val bool) (bool, error) {
+	this.extra.structuralDiff = val
+	return true, nil
+}
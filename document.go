@@ -0,0 +1,214 @@
+package tidy
+
+/*
+#cgo CFLAGS: -I/usr/include/tidy
+#cgo LDFLAGS: -ltidy -L/usr/local/lib
+#include "tidy_compat.h"
+*/
+import "C"
+import (
+	"errors"
+)
+
+// Parse parses and repairs input and runs the diagnostics, but produces no
+// output. The repaired document is kept so that it can be saved any number of
+// times with Save and friends without paying for parsing and repair again,
+// except that each other output format among HTML, XHTML and XML takes one
+// more parse; see SaveXHTML. It replaces any document parsed before.
+//
+// The error, if any, holds the warnings and errors libtidy reported; the
+// document can still be saved.
+func (this *Tidy) Parse(input []byte) error {
+	rc, err := this.parse(input)
+	if err != nil {
+		return err
+	}
+	if rc > 0 {
+		return this.diagnosticsError()
+	}
+	return nil
+}
+
+// Save writes out the document last parsed using the current options and any
+// overrides, which apply to this call only.
+//
+// Options only take effect where libtidy consults them when writing out:
+// wrapping, indentation, encoding and so on. Repairs made by Parse, such as
+// clean or word-2000, stay as the options then in effect made them. So do
+// the ones for the output format, which is why a different format should be
+// asked for with SaveHTML, SaveXHTML or SaveXML rather than an override.
+func (this *Tidy) Save(overrides ...Option) (*Result, error) {
+	if this.parsed < 0 {
+		return nil, errors.New("No document has been parsed")
+	}
+	if len(overrides) > 0 {
+		saved := this.saveConfig()
+		defer this.restoreConfig(saved)
+		if err := this.Apply(overrides...); err != nil {
+			return nil, err
+		}
+	}
+	res, err := this.save(this.parsed)
+	if res != nil && this.parsed > 0 {
+		err = nil // Already reported by Parse.
+	}
+	return res, err
+}
+
+// Output formats, which libtidy partly converts to while repairing.
+const (
+	formatHTML = iota
+	formatXHTML
+	formatXML
+)
+
+// outputFormat returns the output format the current options ask for.
+func (this *Tidy) outputFormat() int {
+	switch {
+	case this.optGetInt(C.TidyXhtmlOut) != 0: // libtidy sets output-xml too
+		return formatXHTML
+	case this.optGetInt(C.TidyXmlOut) != 0:
+		return formatXML
+	}
+	return formatHTML
+}
+
+// SaveHTML saves the parsed document as HTML. See SaveXHTML.
+func (this *Tidy) SaveHTML(overrides ...Option) (string, error) {
+	return this.saveFormat(formatHTML, overrides, With("output-xml", false), With("output-xhtml", false), WithOutputHtml())
+}
+
+// SaveXHTML saves the parsed document as XHTML. libtidy makes part of the
+// conversion, such as adding the XHTML namespace, while repairing rather
+// than while writing, so a document parsed for another output format is
+// parsed once more, from the same input and with the options then current,
+// the first time it is saved as XHTML. That copy is kept alongside the one
+// Parse made until the next document is parsed. See Save for the overrides.
+func (this *Tidy) SaveXHTML(overrides ...Option) (string, error) {
+	return this.saveFormat(formatXHTML, overrides, With("output-xml", false), With("output-html", false), WithOutputXhtml())
+}
+
+// SaveXML saves the parsed document as XML. See SaveXHTML.
+func (this *Tidy) SaveXML(overrides ...Option) (string, error) {
+	return this.saveFormat(formatXML, overrides, With("output-xhtml", false), With("output-html", false), WithOutputXml())
+}
+
+// SaveBodyOnly saves the contents of the parsed document's body, in the
+// current output format, as a fragment. See Save.
+func (this *Tidy) SaveBodyOnly(overrides ...Option) (string, error) {
+	return this.saveAs(overrides, WithShowBodyOnly(True))
+}
+
+// formatDoc is the parsed document repaired for another output format.
+type formatDoc struct {
+	tdoc   C.TidyDoc
+	parsed C.int
+}
+
+// saveFormat saves the document in the given format, which opts select,
+// from the copy repaired for that format if the document wasn't.
+func (this *Tidy) saveFormat(format int, overrides []Option, opts ...Option) (string, error) {
+	if this.parsed < 0 || this.format == format {
+		return this.saveAs(overrides, opts...)
+	}
+	doc, err := this.formatDoc(format, opts)
+	if err != nil {
+		return "", err
+	}
+
+	tdoc, parsed, diagnostics := this.tdoc, this.parsed, this.diagnostics
+	defer func() { this.tdoc, this.parsed, this.diagnostics = tdoc, parsed, diagnostics }()
+	C.tidyOptCopyConfig(doc.tdoc, tdoc) // Saved with the current options
+	this.tdoc, this.parsed = doc.tdoc, doc.parsed
+	return this.saveAs(overrides, opts...)
+}
+
+// formatDoc returns the copy of the document repaired for the given format,
+// which opts select, parsing it the first time it is asked for.
+func (this *Tidy) formatDoc(format int, opts []Option) (formatDoc, error) {
+	if doc, ok := this.formats[format]; ok {
+		return doc, nil
+	}
+
+	doc := formatDoc{tdoc: C.tidyCreate()}
+	C.tidyOptCopyConfig(doc.tdoc, this.tdoc)
+
+	// parse works on this.tdoc and forgets the document it replaces, so
+	// put both back afterwards.
+	tdoc, parsed, source, parsedFormat := this.tdoc, this.parsed, this.source, this.format
+	formats, diagnostics := this.formats, this.diagnostics
+	this.tdoc, this.formats = doc.tdoc, nil
+	defer func() {
+		this.tdoc, this.parsed, this.source, this.format = tdoc, parsed, source, parsedFormat
+		this.formats, this.diagnostics = formats, diagnostics
+	}()
+
+	err := this.Apply(opts...)
+	if err == nil {
+		doc.parsed, err = this.parse(source)
+	}
+	if err != nil {
+		C.tidyRelease(doc.tdoc)
+		return formatDoc{}, err
+	}
+	if formats == nil {
+		formats = make(map[int]formatDoc)
+	}
+	formats[format] = doc
+	return doc, nil
+}
+
+// releaseFormats frees the copies of the document made for other formats.
+func (this *Tidy) releaseFormats() {
+	for _, doc := range this.formats {
+		C.tidyRelease(doc.tdoc)
+	}
+	this.formats = nil
+}
+
+func (this *Tidy) saveAs(overrides []Option, format ...Option) (string, error) {
+	res, err := this.Save(append(format, overrides...)...)
+	if res == nil {
+		return "", err
+	}
+	return res.String(), err
+}
+
+// Diagnostics returns the messages reported about the last document tidied,
+// less any that were filtered out.
+func (this *Tidy) Diagnostics() []Diagnostic {
+	return this.diagnostics
+}
+
+// Fixes splits the diagnostics reported about the last document into the
+// repairs libtidy made on its own and the problems it left alone.
+func (this *Tidy) Fixes() ([]Fix, []Diagnostic) {
+	return splitFixes(this.diagnostics)
+}
+
+// Lint parses input and runs the diagnostics without writing any output, and
+// without forcing output for documents with errors. Use it to validate
+// documents whose tidied form isn't wanted. The error is only for severe
+// errors; problems with the document are in the Report.
+func (this *Tidy) Lint(input []byte) (Report, error) {
+	if _, err := this.parse(input); err != nil {
+		return Report{}, err
+	}
+	return Report{Diagnostics: this.diagnostics}, nil
+}
+
+// Audit parses input and runs the accessibility checks up to the given
+// priority on it, leaving the other options as they were. It produces no
+// output; the document is kept as if given to Parse.
+func (this *Tidy) Audit(input []byte, level AccessLevel) (*AccessibilityReport, error) {
+	saved := this.saveConfig()
+	defer this.restoreConfig(saved)
+	if _, err := this.AccessibilityCheck(level); err != nil {
+		return nil, err
+	}
+
+	if _, err := this.parse(input); err != nil {
+		return nil, err
+	}
+	return newAccessibilityReport(level, this.diagnostics), nil
+}
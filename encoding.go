@@ -0,0 +1,194 @@
+package tidy
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"regexp"
+	"strings"
+)
+
+// encodingLabels maps charset labels, as found in HTTP headers, XML
+// declarations and <meta> tags, onto the encodings libtidy supports. The
+// labels are those of the WHATWG Encoding Standard, which also decides that
+// the ISO-8859-1 and US-ASCII labels mean windows-1252, as browsers do.
+var encodingLabels = map[string]Encoding{
+	"unicode-1-1-utf-8": Utf8,
+	"unicode11utf8":     Utf8,
+	"unicode20utf8":     Utf8,
+	"utf-8":             Utf8,
+	"utf8":              Utf8,
+	"x-unicode20utf8":   Utf8,
+
+	"ansi_x3.4-1968":  Win1252,
+	"ascii":           Win1252,
+	"cp1252":          Win1252,
+	"cp819":           Win1252,
+	"csisolatin1":     Win1252,
+	"ibm819":          Win1252,
+	"iso-8859-1":      Win1252,
+	"iso-ir-100":      Win1252,
+	"iso8859-1":       Win1252,
+	"iso88591":        Win1252,
+	"iso_8859-1":      Win1252,
+	"iso_8859-1:1987": Win1252,
+	"l1":              Win1252,
+	"latin1":          Win1252,
+	"us-ascii":        Win1252,
+	"windows-1252":    Win1252,
+	"x-cp1252":        Win1252,
+
+	"csisolatin9": Latin0,
+	"iso-8859-15": Latin0,
+	"iso8859-15":  Latin0,
+	"iso885915":   Latin0,
+	"iso_8859-15": Latin0,
+	"l9":          Latin0,
+	"latin9":      Latin0,
+
+	"csmacintosh": Mac,
+	"mac":         Mac,
+	"macintosh":   Mac,
+	"x-mac-roman": Mac,
+
+	"ccsid00858": Ibm858,
+	"cp00858":    Ibm858,
+	"cp858":      Ibm858,
+	"ibm00858":   Ibm858,
+	"ibm858":     Ibm858,
+
+	"utf-16":   Utf16,
+	"utf-16be": Utf16be,
+	"utf-16le": Utf16le,
+
+	"big5":       Big5,
+	"big5-hkscs": Big5,
+	"cn-big5":    Big5,
+	"csbig5":     Big5,
+	"x-x-big5":   Big5,
+
+	"csshiftjis":  Shiftjis,
+	"ms932":       Shiftjis,
+	"ms_kanji":    Shiftjis,
+	"shift-jis":   Shiftjis,
+	"shift_jis":   Shiftjis,
+	"sjis":        Shiftjis,
+	"windows-31j": Shiftjis,
+	"x-sjis":      Shiftjis,
+
+	"csiso2022jp": Iso2022,
+	"iso-2022-jp": Iso2022,
+}
+
+// EncodingForLabel returns the libtidy encoding for an IANA or WHATWG charset
+// label such as "windows-1252" or "Shift_JIS". It returns an error for labels
+// naming an encoding libtidy cannot read.
+func EncodingForLabel(label string) (Encoding, error) {
+	if e, ok := encodingLabels[normalizeLabel(label)]; ok {
+		return e, nil
+	}
+	return Raw, fmt.Errorf("Encoding %q is not supported by libtidy", label)
+}
+
+// Charset returns the IANA name of the encoding, as used in Content-Type
+// headers, or an empty string for Raw.
+func (e Encoding) Charset() string {
+	if e >= 0 && int(e) < len(encodingCharsets) {
+		return encodingCharsets[e]
+	}
+	return ""
+}
+
+var encodingCharsets = []string{"", "US-ASCII", "ISO-8859-15", "ISO-8859-1", "UTF-8", "ISO-2022-JP", "macintosh", "windows-1252", "IBM00858", "UTF-16LE", "UTF-16BE", "UTF-16", "Big5", "Shift_JIS"}
+
+// prescanLength is how far into a document DetectCharset looks for an XML
+// declaration or <meta> tag; the HTML standard settles on 1024 bytes.
+const prescanLength = 1024
+
+var (
+	xmlDeclEncoding = regexp.MustCompile(`^<\?xml[^>]*\sencoding\s*=\s*["']([^"']+)["']`)
+	metaTag         = regexp.MustCompile(`(?i)<meta\s[^>]*>`)
+	metaAttribute   = regexp.MustCompile(`([a-zA-Z-]+)\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+	contentCharset  = regexp.MustCompile(`(?i)charset\s*=\s*["']?([^\s"';]+)`)
+)
+
+// DetectCharset returns the charset label a document declares, lower-cased,
+// or an empty string if it declares none. As in a browser, a byte order mark
+// takes precedence, then the charset parameter of the HTTP Content-Type
+// header (which may be empty), then an XML declaration or <meta> tag within
+// the first 1024 bytes of the document. The label may name a charset libtidy
+// cannot read; see EncodingForLabel.
+func DetectCharset(src []byte, contentType string) string {
+	switch {
+	case bytes.HasPrefix(src, []byte{0xEF, 0xBB, 0xBF}):
+		return "utf-8"
+	case bytes.HasPrefix(src, []byte{0xFE, 0xFF}):
+		return "utf-16be"
+	case bytes.HasPrefix(src, []byte{0xFF, 0xFE}):
+		return "utf-16le"
+	}
+
+	if contentType != "" {
+		if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+			return normalizeLabel(params["charset"])
+		}
+	}
+
+	if len(src) > prescanLength {
+		src = src[:prescanLength]
+	}
+	if m := xmlDeclEncoding.FindSubmatch(src); m != nil {
+		return normalizeLabel(string(m[1]))
+	}
+	for _, tag := range metaTag.FindAll(src, -1) {
+		var httpEquiv, content string
+		for _, attr := range metaAttribute.FindAllSubmatch(tag, -1) {
+			val := strings.Trim(string(attr[2]), `"'`)
+			switch strings.ToLower(string(attr[1])) {
+			case "charset":
+				return normalizeLabel(val)
+			case "http-equiv":
+				httpEquiv = strings.ToLower(val)
+			case "content":
+				content = val
+			}
+		}
+		if httpEquiv == "content-type" {
+			if m := contentCharset.FindStringSubmatch(content); m != nil {
+				return normalizeLabel(m[1])
+			}
+		}
+	}
+	return ""
+}
+
+// relabelCharset rewrites the charset src declares in an XML declaration or
+// <meta> tag within its first 1024 bytes, if it declares one, to charset.
+func relabelCharset(src []byte, charset string) []byte {
+	head := src
+	if len(head) > prescanLength {
+		head = head[:prescanLength]
+	}
+	var start, end int
+	if m := xmlDeclEncoding.FindSubmatchIndex(head); m != nil {
+		start, end = m[2], m[3]
+	} else {
+		for _, tag := range metaTag.FindAllIndex(head, -1) {
+			if m := contentCharset.FindSubmatchIndex(head[tag[0]:tag[1]]); m != nil {
+				start, end = tag[0]+m[2], tag[0]+m[3]
+				break
+			}
+		}
+	}
+	if start == end {
+		return src
+	}
+	out := make([]byte, 0, len(src)+len(charset))
+	out = append(out, src[:start]...)
+	out = append(out, charset...)
+	return append(out, src[end:]...)
+}
+
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.TrimSpace(label))
+}
@@ -0,0 +1,61 @@
+package tidy
+
+import (
+	"testing"
+)
+
+func Test_EncodingForLabel(t *testing.T) {
+	labels := map[string]Encoding{
+		"UTF-8":        Utf8,
+		" utf8 ":       Utf8,
+		"windows-1252": Win1252,
+		"ISO-8859-1":   Win1252,
+		"latin9":       Latin0,
+		"Shift_JIS":    Shiftjis,
+		"utf-16le":     Utf16le,
+	}
+	for label, want := range labels {
+		if got, err := EncodingForLabel(label); err != nil || got != want {
+			t.Errorf("EncodingForLabel(%q) = %s, %v; want %s", label, got, err, want)
+		}
+	}
+	if _, err := EncodingForLabel("koi8-r"); err == nil {
+		t.Errorf("A charset libtidy can't read must be reported")
+	}
+}
+
+func Test_DetectCharset(t *testing.T) {
+	tests := []struct {
+		src         string
+		contentType string
+		want        string
+	}{
+		{"\xEF\xBB\xBF<p>Hi", "text/html; charset=latin1", "utf-8"},
+		{"\xFF\xFE<\x00p\x00", "", "utf-16le"},
+		{`<meta charset="shift_jis">`, "text/html; charset=Windows-1251", "windows-1251"},
+		{`<?xml version="1.0" encoding="ISO-8859-15"?><html/>`, "", "iso-8859-15"},
+		{`<html><head><meta charset=utf-8><title>`, "text/html", "utf-8"},
+		{`<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=koi8-r">`, "", "koi8-r"},
+		{`<meta content='text/html; charset=big5' http-equiv='content-type'>`, "", "big5"},
+		{`<meta name="charset" content="utf-8"><p>No charset here`, "", ""},
+	}
+	for _, test := range tests {
+		if got := DetectCharset([]byte(test.src), test.contentType); got != test.want {
+			t.Errorf("DetectCharset(%q, %q) = %q; want %q", test.src, test.contentType, got, test.want)
+		}
+	}
+}
+
+func Test_RelabelCharset(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{`<meta charset="windows-1251"><title>`, `<meta charset="utf-8"><title>`},
+		{`<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=koi8-r">`, `<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=utf-8">`},
+		{`<?xml version="1.0" encoding="ISO-8859-2"?><html/>`, `<?xml version="1.0" encoding="utf-8"?><html/>`},
+		{`<meta name="charset" content="koi8-r"><p>No charset here`, `<meta name="charset" content="koi8-r"><p>No charset here`},
+	}
+	for _, test := range tests {
+		if got := string(relabelCharset([]byte(test.src), "utf-8")); got != test.want {
+			t.Errorf("relabelCharset(%q) = %q; want %q", test.src, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package tidy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Encoding is a character encoding libtidy can read and write. See
+// CharEncoding for the values.
+type Encoding int
+
+const Raw Encoding = 0
+const Ascii Encoding = 1
+const Latin0 Encoding = 2
+const Latin1 Encoding = 3
+const Utf8 Encoding = 4
+const Iso2022 Encoding = 5
+const Mac Encoding = 6
+const Win1252 Encoding = 7
+const Ibm858 Encoding = 8
+const Utf16le Encoding = 9
+const Utf16be Encoding = 10
+const Utf16 Encoding = 11
+const Big5 Encoding = 12
+const Shiftjis Encoding = 13
+
+// AutoBool is an option value that may be False, True or Auto.
+type AutoBool int
+
+const False AutoBool = 0
+const True AutoBool = 1
+const Auto AutoBool = 2
+
+// NewlineMode is the line ending Tidy writes. See Newline.
+type NewlineMode int
+
+const LF NewlineMode = 0
+const CRLF NewlineMode = 1
+const CR NewlineMode = 2
+
+// DuplicateAttrs says which of a repeated attribute Tidy keeps. See
+// RepeatedAttributes.
+type DuplicateAttrs int
+
+const KeepFirst DuplicateAttrs = 0
+const KeepLast DuplicateAttrs = 1
+
+// SortStrategy is how Tidy orders the attributes of an element. See
+// SortAttributes.
+type SortStrategy int
+
+const None SortStrategy = 0
+const Alpha SortStrategy = 1
+
+// AccessLevel is the priority of accessibility checks Tidy performs. See
+// AccessibilityCheck.
+type AccessLevel int
+
+const TidyClassic AccessLevel = 0
+const Priority1Checks AccessLevel = 1
+const Priority2Checks AccessLevel = 2
+const Priority3Checks AccessLevel = 3
+
+// The names below are the ones libtidy uses in configuration files and on
+// the command line; Parse functions accept them case-insensitively.
+
+var encodingNames = []string{"raw", "ascii", "latin0", "latin1", "utf8", "iso2022", "mac", "win1252", "ibm858", "utf16le", "utf16be", "utf16", "big5", "shiftjis"}
+
+var autoBoolNames = []string{"no", "yes", "auto"}
+
+var newlineNames = []string{"LF", "CRLF", "CR"}
+
+var duplicateAttrsNames = []string{"keep-first", "keep-last"}
+
+var sortStrategyNames = []string{"none", "alpha"}
+
+var accessLevelNames = []string{"tidy-classic", "priority-1", "priority-2", "priority-3"}
+
+func (e Encoding) String() string {
+	return enumString("Encoding", encodingNames, int(e))
+}
+
+func (a AutoBool) String() string {
+	return enumString("AutoBool", autoBoolNames, int(a))
+}
+
+func (n NewlineMode) String() string {
+	return enumString("NewlineMode", newlineNames, int(n))
+}
+
+func (d DuplicateAttrs) String() string {
+	return enumString("DuplicateAttrs", duplicateAttrsNames, int(d))
+}
+
+func (s SortStrategy) String() string {
+	return enumString("SortStrategy", sortStrategyNames, int(s))
+}
+
+func (a AccessLevel) String() string {
+	return enumString("AccessLevel", accessLevelNames, int(a))
+}
+
+// ParseEncoding returns the Encoding with the given libtidy name, e.g. "utf8".
+func ParseEncoding(s string) (Encoding, error) {
+	v, err := enumParse("Encoding", encodingNames, s)
+	return Encoding(v), err
+}
+
+// ParseAutoBool returns the AutoBool named by s. Besides libtidy's "yes",
+// "no" and "auto" it accepts "true" and "false".
+func ParseAutoBool(s string) (AutoBool, error) {
+	switch strings.ToLower(s) {
+	case "true":
+		return True, nil
+	case "false":
+		return False, nil
+	}
+	v, err := enumParse("AutoBool", autoBoolNames, s)
+	return AutoBool(v), err
+}
+
+// ParseNewlineMode returns the NewlineMode named by s: "LF", "CRLF" or "CR".
+func ParseNewlineMode(s string) (NewlineMode, error) {
+	v, err := enumParse("NewlineMode", newlineNames, s)
+	return NewlineMode(v), err
+}
+
+// ParseDuplicateAttrs returns the DuplicateAttrs named by s: "keep-first" or
+// "keep-last".
+func ParseDuplicateAttrs(s string) (DuplicateAttrs, error) {
+	v, err := enumParse("DuplicateAttrs", duplicateAttrsNames, s)
+	return DuplicateAttrs(v), err
+}
+
+// ParseSortStrategy returns the SortStrategy named by s: "none" or "alpha".
+func ParseSortStrategy(s string) (SortStrategy, error) {
+	v, err := enumParse("SortStrategy", sortStrategyNames, s)
+	return SortStrategy(v), err
+}
+
+// ParseAccessLevel returns the AccessLevel named by s. It accepts the names
+// returned by String as well as libtidy's numeric levels "0" to "3".
+func ParseAccessLevel(s string) (AccessLevel, error) {
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n < len(accessLevelNames) {
+		return AccessLevel(n), nil
+	}
+	v, err := enumParse("AccessLevel", accessLevelNames, s)
+	return AccessLevel(v), err
+}
+
+func enumString(typ string, names []string, v int) string {
+	if v >= 0 && v < len(names) {
+		return names[v]
+	}
+	return fmt.Sprintf("%s(%d)", typ, v)
+}
+
+func enumParse(typ string, names []string, s string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(name, s) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown %s %q", typ, s)
+}
@@ -0,0 +1,107 @@
+package tidy
+
+import (
+	"errors"
+	"strings"
+)
+
+// Fake is a Tidier that tidies nothing. It records the options applied to it
+// and returns the output and diagnostics scripted for each input, so that
+// code using GoTidy can be tested without cgo or libtidy. The zero value is
+// ready to use and returns every input unchanged.
+type Fake struct {
+	Outputs map[string]string       // The output for each input; others come back as they are.
+	Reports map[string][]Diagnostic // The diagnostics for each input; others have none.
+	Err     error                   // If set, Tidy, TidyBytes and Lint return it instead.
+
+	Options []Option   // Every option applied, in order.
+	Calls   []FakeCall // Every document tidied or linted, in order.
+	Freed   bool
+
+	diagnostics []Diagnostic
+}
+
+// FakeCall is a document given to a Fake, with the overrides it was given.
+type FakeCall struct {
+	Input     string
+	Overrides []Option
+}
+
+var _ Tidier = (*Fake)(nil)
+
+// Apply records opts. Unlike Tidy.Apply, it accepts any name and value.
+func (f *Fake) Apply(opts ...Option) error {
+	f.Options = append(f.Options, opts...)
+	return nil
+}
+
+// Option returns the value last applied for the named option, and whether
+// there was one. Overrides don't count, as they apply to a single call.
+func (f *Fake) Option(name string) (interface{}, bool) {
+	for i := len(f.Options) - 1; i >= 0; i-- {
+		if f.Options[i].Name() == name {
+			return f.Options[i].Value(), true
+		}
+	}
+	return nil, false
+}
+
+// Tidy returns the output scripted for htmlSource. Like Tidy.Tidy, it returns
+// an error listing the diagnostics if any is a warning or worse.
+func (f *Fake) Tidy(htmlSource string, overrides ...Option) (string, error) {
+	res, err := f.TidyBytes([]byte(htmlSource), overrides...)
+	if res == nil {
+		return "", err
+	}
+	return res.String(), err
+}
+
+// TidyBytes is Tidy returning a Result, always in UTF-8.
+func (f *Fake) TidyBytes(input []byte, overrides ...Option) (*Result, error) {
+	if err := f.run(input, overrides); err != nil {
+		return nil, err
+	}
+	out, ok := f.Outputs[string(input)]
+	if !ok {
+		out = string(input)
+	}
+	res := &Result{Raw: []byte(out), Encoding: Utf8, Charset: Utf8.Charset(), text: out}
+	return res, f.diagnosticsError()
+}
+
+// Lint returns a Report of the diagnostics scripted for input.
+func (f *Fake) Lint(input []byte) (Report, error) {
+	if err := f.run(input, nil); err != nil {
+		return Report{}, err
+	}
+	return Report{Diagnostics: f.diagnostics}, nil
+}
+
+// Diagnostics returns the diagnostics scripted for the last input.
+func (f *Fake) Diagnostics() []Diagnostic {
+	return f.diagnostics
+}
+
+// Free marks the Fake freed, for tests to check that it was.
+func (f *Fake) Free() {
+	f.Freed = true
+}
+
+func (f *Fake) run(input []byte, overrides []Option) error {
+	f.Calls = append(f.Calls, FakeCall{string(input), overrides})
+	f.diagnostics = f.Reports[string(input)]
+	return f.Err
+}
+
+func (f *Fake) diagnosticsError() error {
+	var lines []string
+	for _, d := range f.diagnostics {
+		if d.Severity >= Warning {
+			lines = append(lines, d.String())
+		}
+	}
+	if lines == nil {
+		return nil
+	}
+	return errors.New(strings.Join(lines, "\n") + "\n")
+}
@@ -0,0 +1,63 @@
+package tidy
+
+import (
+	"errors"
+	"testing"
+)
+
+// clean stands for code under test that takes a Tidier.
+func clean(t Tidier, page string) (string, int, error) {
+	if err := t.Apply(WithIndent(Auto), With("wrap", 0)); err != nil {
+		return "", 0, err
+	}
+	out, err := t.Tidy(page, WithShowBodyOnly(True))
+	return out, len(t.Diagnostics()), err
+}
+
+func Test_Fake(t *testing.T) {
+	fake := &Fake{
+		Outputs: map[string]string{"<p>One": "<p>One</p>\n"},
+		Reports: map[string][]Diagnostic{"<p>One": {
+			{Severity: Info, Message: "Document content looks like HTML5"},
+			{Code: MissingEndtagFor, Severity: Warning, Line: 1, Column: 1, Message: "missing </p>"},
+		}},
+	}
+
+	out, n, err := clean(fake, "<p>One")
+	if out != "<p>One</p>\n" || n != 2 {
+		t.Errorf("Got %q and %d diagnostics", out, n)
+	}
+	if err == nil || err.Error() != "line 1 column 1 - Warning: missing </p>\n" {
+		t.Errorf("Warnings must be returned as an error, got %v", err)
+	}
+	if v, _ := fake.Option("indent"); v != Auto {
+		t.Errorf("indent was recorded as %v", v)
+	}
+	if v, _ := fake.Option("wrap"); v != 0 {
+		t.Errorf("wrap was recorded as %v", v)
+	}
+	if _, ok := fake.Option("show-body-only"); ok {
+		t.Errorf("Overrides must not be recorded as options")
+	}
+	if len(fake.Calls) != 1 || fake.Calls[0].Overrides[0].Name() != "show-body-only" {
+		t.Errorf("Calls recorded as %v", fake.Calls)
+	}
+
+	if out, _, err := clean(fake, "<b>As is</b>"); out != "<b>As is</b>" || err != nil {
+		t.Errorf("Unscripted input must come back unchanged, got %q, %v", out, err)
+	}
+
+	r, _ := fake.Lint([]byte("<p>One"))
+	if r.Count(Warning) != 1 {
+		t.Errorf("Lint must report the scripted diagnostics")
+	}
+
+	fake.Err = errors.New("severe")
+	if _, _, err := clean(fake, "<p>One"); err != fake.Err {
+		t.Errorf("Err must be returned, got %v", err)
+	}
+	fake.Free()
+	if !fake.Freed {
+		t.Errorf("Free must be recorded")
+	}
+}
@@ -0,0 +1,129 @@
+package tidy
+
+/*
+#cgo CFLAGS: -I/usr/include/tidy
+#cgo LDFLAGS: -ltidy -L/usr/local/lib
+#include "tidy_compat.h"
+*/
+import "C"
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"unsafe"
+)
+
+// This option specifies the suffix of the backup TidyFile makes of a file before replacing it, e.g. ".orig". If empty, the default, no backup is made.
+func (this *Tidy) BackupSuffix(val string) (bool, error) {
+	this.extra.backupSuffix = val
+	return true, nil
+}
+
+// TidyFile tidies the file at inPath and writes the result to outPath. If
+// outPath is empty, the output goes back to inPath when WriteBack is set and
+// otherwise to the file given by OutputFile.
+//
+// The output is written to a temporary file that is then renamed over
+// outPath, so that readers never see a half-written file. If BackupSuffix is
+// set, the file being replaced is kept under that suffix. When a file is
+// tidied in place and KeepTime is set, its modification time is preserved.
+// If ErrorFile is set, the diagnostics are written to it.
+//
+// The file goes through the same steps as with TidyBytes, AutoDetectEncoding,
+// Stabilize and MapPositions included. As with Tidy, the error holds the
+// warnings and errors libtidy reported; the output is written regardless.
+func (this *Tidy) TidyFile(inPath, outPath string) error {
+	if outPath == "" {
+		if this.optGetInt(C.TidyWriteBack) != 0 {
+			outPath = inPath
+		} else if outPath = this.optGetString(C.TidyOutFile); outPath == "" {
+			return errors.New("No output file given and write-back is not set")
+		}
+	}
+
+	info, err := os.Stat(inPath)
+	if err != nil {
+		return err
+	}
+	input, err := ioutil.ReadFile(inPath)
+	if err != nil {
+		return err
+	}
+
+	rc, err := this.parse(input)
+	if err != nil {
+		return err
+	}
+	if errFile := this.optGetString(C.TidyErrFile); errFile != "" {
+		diagnostics := C.GoBytes(unsafe.Pointer(this.errbuf.bp), C.int(this.errbuf.size))
+		if err := ioutil.WriteFile(errFile, diagnostics, 0666); err != nil {
+			return err
+		}
+	}
+	res, tidyErr := this.stabilize(this.save(rc))
+	if res == nil {
+		return tidyErr
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(outPath), "."+filepath.Base(outPath)+".tidy")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // In case we don't get as far as renaming it.
+	_, err = tmp.Write(res.Raw)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	mode := info.Mode()
+	if existing, err := os.Stat(outPath); err == nil {
+		mode = existing.Mode()
+		if this.extra.backupSuffix != "" {
+			if err := backup(outPath, outPath+this.extra.backupSuffix); err != nil {
+				return err
+			}
+		}
+	}
+	if err := os.Chmod(tmpPath, mode.Perm()); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, outPath); err != nil {
+		return err
+	}
+
+	if outPath == inPath && this.optGetInt(C.TidyKeepFileTimes) != 0 {
+		if err := os.Chtimes(outPath, info.ModTime(), info.ModTime()); err != nil {
+			return err
+		}
+	}
+
+	return tidyErr
+}
+
+// backup makes a copy of path at backupPath, as a hard link if possible.
+func backup(path, backupPath string) error {
+	os.Remove(backupPath)
+	if os.Link(path, backupPath) == nil {
+		return nil
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(backupPath)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
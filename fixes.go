@@ -0,0 +1,119 @@
+package tidy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FixKind is the kind of change libtidy made to repair a document.
+type FixKind int
+
+const (
+	Inserted  FixKind = iota // A missing element, end tag or attribute was added.
+	Discarded                // An unexpected element or end tag was dropped.
+	Moved                    // Content was moved to where it is allowed.
+	Replaced                 // An element was swapped for another.
+	Trimmed                  // An empty element was removed.
+	Repaired                 // An attribute or its value was corrected.
+)
+
+var fixKindNames = []string{"inserted", "discarded", "moved", "replaced", "trimmed", "repaired"}
+
+func (k FixKind) String() string {
+	if k < 0 || int(k) >= len(fixKindNames) {
+		return fmt.Sprintf("FixKind(%d)", int(k))
+	}
+	return fixKindNames[k]
+}
+
+// Fix is a change libtidy made on its own to repair a document. Line and
+// Column are the position in the input.
+type Fix struct {
+	Kind    FixKind
+	Element string // Name of the element involved, e.g. "p", if known.
+	Code    MessageCode
+	Line    int
+	Column  int
+	Message string
+}
+
+func (f Fix) String() string {
+	return fmt.Sprintf("line %d column %d - %s <%s>: %s", f.Line, f.Column, f.Kind, f.Element, f.Message)
+}
+
+// fixCodes gives the kind of repair behind each message that reports one.
+var fixCodes = map[MessageCode]FixKind{
+	MissingEndtagFor:          Inserted,
+	MissingEndtagBefore:       Inserted,
+	MissingStarttag:           Inserted,
+	InsertingTag:              Inserted,
+	InsertingAutoAttribute:    Inserted,
+	MissingTitleElement:       Inserted,
+	AddedMissingCharset:       Inserted,
+	DiscardingUnexpected:      Discarded,
+	MalformedCommentDropping:  Discarded,
+	MovedStyleToHead:          Moved,
+	ContentAfterBody:          Moved,
+	ReplacingElement:          Replaced,
+	ReplacingUnexElement:      Replaced,
+	CoerceToEndtag:            Replaced,
+	UsingBrInplaceOf:          Replaced,
+	TrimEmptyElement:          Trimmed,
+	JoiningAttribute:          Repaired,
+	RepeatedAttribute:         Repaired,
+	FixedBackslash:            Repaired,
+	EscapedIllegalUrl:         Repaired,
+	AttributeValueReplaced:    Repaired,
+	BadAttributeValueReplaced: Repaired,
+}
+
+// fixTexts recognises the same messages by their English text, for libraries
+// without message codes.
+var fixTexts = []struct {
+	pattern *regexp.Regexp
+	kind    FixKind
+}{
+	{regexp.MustCompile(`^missing </?\w|^inserting |^adding missing`), Inserted},
+	{regexp.MustCompile(`^discarding `), Discarded},
+	{regexp.MustCompile(`^moved |content occurs after end of body`), Moved},
+	{regexp.MustCompile(`^replacing |^using <br> in place of`), Replaced},
+	{regexp.MustCompile(`^trimming empty `), Trimmed},
+	{regexp.MustCompile(`^joining values of repeated|repeated attribute|^converting backslash|^escaping malformed URI`), Repaired},
+}
+
+var fixElement = regexp.MustCompile(`</?([A-Za-z][\w:-]*)|'(\w+)' element`)
+
+// Fixes splits the report like Tidy.Fixes.
+func (r Report) Fixes() ([]Fix, []Diagnostic) {
+	return splitFixes(r.Diagnostics)
+}
+
+func splitFixes(diags []Diagnostic) (fixes []Fix, unfixed []Diagnostic) {
+	for _, d := range diags {
+		kind, ok := fixKind(d)
+		if !ok {
+			unfixed = append(unfixed, d)
+			continue
+		}
+		f := Fix{Kind: kind, Code: d.Code, Line: d.Line, Column: d.Column, Message: d.Message}
+		if m := fixElement.FindStringSubmatch(d.Message); m != nil {
+			f.Element = strings.ToLower(m[1] + m[2])
+		}
+		fixes = append(fixes, f)
+	}
+	return fixes, unfixed
+}
+
+func fixKind(d Diagnostic) (FixKind, bool) {
+	if d.Code != "" {
+		kind, ok := fixCodes[d.Code]
+		return kind, ok
+	}
+	for _, t := range fixTexts {
+		if t.pattern.MatchString(d.Message) {
+			return t.kind, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,38 @@
+package tidy
+
+import (
+	"testing"
+)
+
+func Test_SplitFixes(t *testing.T) {
+	diags := []Diagnostic{
+		{Severity: Warning, Line: 1, Column: 1, Message: "missing <!DOCTYPE> declaration"},
+		{Severity: Warning, Line: 1, Column: 4, Message: "missing </b> before </p>"},
+		{Severity: Warning, Line: 2, Column: 1, Message: "discarding unexpected </div>"},
+		{Severity: Warning, Line: 3, Column: 1, Message: "inserting missing 'title' element"},
+		{Severity: Warning, Line: 4, Column: 5, Message: "trimming empty <p>"},
+		{Severity: Error, Line: 5, Column: 1, Message: "<foo> is not recognized!"},
+		{Code: TrimEmptyElement, Severity: Warning, Line: 6, Column: 1, Message: "elément <span> vide supprimé"},
+	}
+	want := []Fix{
+		{Kind: Inserted, Element: "b", Line: 1, Column: 4},
+		{Kind: Discarded, Element: "div", Line: 2, Column: 1},
+		{Kind: Inserted, Element: "title", Line: 3, Column: 1},
+		{Kind: Trimmed, Element: "p", Line: 4, Column: 5},
+		{Kind: Trimmed, Element: "span", Code: TrimEmptyElement, Line: 6, Column: 1},
+	}
+
+	fixes, unfixed := splitFixes(diags)
+	if len(fixes) != len(want) {
+		t.Fatalf("Got %d fixes %v; want %d", len(fixes), fixes, len(want))
+	}
+	for i, w := range want {
+		w.Message = fixes[i].Message
+		if fixes[i] != w {
+			t.Errorf("Fix %d = %+v; want %+v", i, fixes[i], w)
+		}
+	}
+	if len(unfixed) != 2 || unfixed[0].Line != 1 || unfixed[1].Line != 5 {
+		t.Errorf("Unfixed diagnostics = %v", unfixed)
+	}
+}
@@ -1,44 +1,39 @@
+//go:build cgo
+// +build cgo
+
 package main
 
 import (
 	"flag"
 	"fmt"
-	"log"
+	"github.com/rniedosmialek/GoTidy"
 	"io/ioutil"
+	"log"
 	"os"
-	"github.com/JalfResi/GoTidy"
 )
 
 var (
-	debug  *bool = flag.Bool("debug", false, "Output debugging messages")  
+	debug  *bool   = flag.Bool("debug", false, "Output debugging messages")
+	preset *string = flag.String("preset", "html-fragment", "Named set of options to tidy with")
 )
 
 func main() {
 	flag.Parse()
 
-	t := tidy.New()
+	opts, err := tidy.Preset(*preset)
+	if err != nil {
+		log.Fatal(err)
+	}
+	t, err := tidy.New(opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
 	defer t.Free()
 
-	t.OutputXml(true)
-	t.AddXmlDecl(false)
-	t.QuoteAmpersand(true)
-	t.TidyMark(false)
-	t.CharEncoding(tidy.Utf8)
-	t.AsciiChars(true)
-	t.NumericEntities(true)
-	t.FixUri(true)
-	t.DropEmptyParas(true)
-	t.DropFontTags(true)
-	t.DropProprietaryAttributes(true)
-	t.FixBackslash(true)
-	t.JoinClasses(true)
-	t.JoinStyles(true)
-	t.ShowBodyOnly(tidy.True)
-
 	in, _ := ioutil.ReadAll(os.Stdin)
 	output, err := t.Tidy(string(in))
 	if *debug == true && err != nil {
 		log.Fatal(err, output)
 	}
 	fmt.Println(output)
-}
\ No newline at end of file
+}
@@ -0,0 +1,54 @@
+//go:build cgo
+// +build cgo
+
+package tidy
+
+import (
+	"fmt"
+	"sync"
+)
+
+// libtidy keeps its message language in a global, so instances that want a
+// language of their own take turns with it.
+var languageMu sync.Mutex
+
+// Languages lists the languages libtidy can write diagnostics in, such as
+// "en", "en_gb", "fr" and "de". Only tidy-html5 has translations; with
+// classic libtidy it returns ErrUnsupportedOption.
+func Languages() ([]string, error) {
+	return languages()
+}
+
+// Language sets the language diagnostics are written in for this instance,
+// for example "fr" or "de_DE"; "" leaves libtidy's default. Only the text is
+// translated: message codes stay the same in every language.
+func (this *Tidy) Language(val string) (bool, error) {
+	if _, err := languages(); err != nil {
+		return false, err
+	}
+	if val != "" {
+		languageMu.Lock()
+		prev, ok := setLanguage(val)
+		setLanguage(prev)
+		languageMu.Unlock()
+		if !ok {
+			return false, fmt.Errorf("Language %q is not installed", val)
+		}
+	}
+	this.extra.language = val
+	return true, nil
+}
+
+// useLanguage switches libtidy to the instance's language, if it has one.
+// The function returned switches it back.
+func (this *Tidy) useLanguage() func() {
+	if this.extra.language == "" {
+		return func() {}
+	}
+	languageMu.Lock()
+	prev, _ := setLanguage(this.extra.language)
+	return func() {
+		setLanguage(prev)
+		languageMu.Unlock()
+	}
+}
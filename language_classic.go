@@ -0,0 +1,14 @@
+//go:build !tidyhtml5 && cgo
+// +build !tidyhtml5,cgo
+
+package tidy
+
+// Classic libtidy only speaks English.
+
+func languages() ([]string, error) {
+	return nil, ErrUnsupportedOption
+}
+
+func setLanguage(code string) (string, bool) {
+	return "", false
+}
@@ -0,0 +1,33 @@
+//go:build tidyhtml5
+// +build tidyhtml5
+
+package tidy
+
+/*
+#cgo CFLAGS: -I/usr/include/tidy
+#include "tidy_compat.h"
+*/
+import "C"
+import (
+	"sort"
+	"unsafe"
+)
+
+func languages() ([]string, error) {
+	var langs []string
+	it := C.getInstalledLanguageList()
+	for it != nil {
+		langs = append(langs, C.GoString(C.getNextInstalledLanguage(&it)))
+	}
+	sort.Strings(langs)
+	return langs, nil
+}
+
+// setLanguage makes code libtidy's message language, returning the language
+// it replaced and whether code was accepted.
+func setLanguage(code string) (string, bool) {
+	prev := C.GoString(C.tidyGetLanguage())
+	c := C.CString(code)
+	defer C.free(unsafe.Pointer(c))
+	return prev, C.tidySetLanguage(c) != 0
+}
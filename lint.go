@@ -0,0 +1,69 @@
+package tidy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Report is the outcome of Lint: what libtidy found wrong with a document.
+type Report struct {
+	Diagnostics []Diagnostic
+}
+
+// Count returns the number of diagnostics of severity s.
+func (r Report) Count(s Severity) int {
+	n := 0
+	for _, d := range r.Diagnostics {
+		if d.Severity == s {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed says whether any diagnostic is at or above the threshold, taking
+// severities in the order Info, Warning, Config, Access, Error, BadDocument,
+// Fatal. Failed(Warning) treats warnings as errors; Failed(Error) lets them
+// through. Info messages never fail a threshold above Info.
+func (r Report) Failed(threshold Severity) bool {
+	for _, d := range r.Diagnostics {
+		if d.Severity >= threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// ExitCode follows the tidy command: 2 if the report fails the threshold, 1
+// if it has warnings (or worse) that don't, and 0 otherwise.
+func (r Report) ExitCode(threshold Severity) int {
+	switch {
+	case r.Failed(threshold):
+		return 2
+	case r.Failed(Warning):
+		return 1
+	}
+	return 0
+}
+
+// Summary counts the warnings and errors the way the tidy command does, for
+// example "2 warnings, 1 error".
+func (r Report) Summary() string {
+	var parts []string
+	add := func(n int, what string) {
+		switch n {
+		case 0:
+		case 1:
+			parts = append(parts, "1 "+what)
+		default:
+			parts = append(parts, fmt.Sprintf("%d %ss", n, what))
+		}
+	}
+	add(r.Count(Warning), "warning")
+	add(r.Count(Access), "accessibility warning")
+	add(r.Count(Error)+r.Count(BadDocument)+r.Count(Fatal), "error")
+	if len(parts) == 0 {
+		return "No warnings or errors were found"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,38 @@
+package tidy
+
+import (
+	"testing"
+)
+
+func Test_Report(t *testing.T) {
+	r := Report{Diagnostics: []Diagnostic{
+		{Severity: Info, Message: "Document content looks like HTML5"},
+		{Severity: Warning, Line: 1, Column: 1, Message: "missing <!DOCTYPE> declaration"},
+		{Severity: Warning, Line: 2, Column: 4, Message: "trimming empty <p>"},
+	}}
+	if r.Count(Warning) != 2 || r.Count(Error) != 0 {
+		t.Errorf("Counted %d warnings and %d errors", r.Count(Warning), r.Count(Error))
+	}
+	if r.Failed(Error) || !r.Failed(Warning) {
+		t.Errorf("Warnings must fail a Warning threshold and only that")
+	}
+	if r.ExitCode(Error) != 1 || r.ExitCode(Warning) != 2 {
+		t.Errorf("ExitCode = %d, %d; want 1, 2", r.ExitCode(Error), r.ExitCode(Warning))
+	}
+	if s := r.Summary(); s != "2 warnings" {
+		t.Errorf("Summary() = %q", s)
+	}
+
+	r.Diagnostics = append(r.Diagnostics, Diagnostic{Severity: Error, Message: "<foo> is not recognized!"})
+	if !r.Failed(Error) || r.ExitCode(Error) != 2 {
+		t.Errorf("An error must fail the default threshold")
+	}
+	if s := r.Summary(); s != "2 warnings, 1 error" {
+		t.Errorf("Summary() = %q", s)
+	}
+
+	clean := Report{Diagnostics: r.Diagnostics[:1]}
+	if clean.Failed(Warning) || clean.ExitCode(Warning) != 0 {
+		t.Errorf("Info messages must be ignored")
+	}
+}
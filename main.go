@@ -4,59 +4,212 @@ package tidy
 /*
 #cgo CFLAGS: -I/usr/include/tidy
 #cgo LDFLAGS: -ltidy -L/usr/local/lib
-#include <tidy.h>
-#include <buffio.h>
-#include <errno.h>
+#include "tidy_compat.h"
 */
 import "C"
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"unsafe"
 )
 
 type Tidy struct {
 	tdoc   C.TidyDoc
 	errbuf C.TidyBuffer
+	extra  extraOptions
+	parsed C.int  // Status of the document last parsed; negative if none.
+	source []byte // The input it was parsed from
+	format int    // The output format it was repaired for
+
+	formats map[int]formatDoc // It, parsed again for other output formats
+
+	diagnostics []Diagnostic // Reported about the last document
+	sourceMap   *SourceMap   // Of the last output, if MapPositions is on
 }
 
-func New() *Tidy {
-	t := &Tidy{}
+var _ Tidier = (*Tidy)(nil)
+
+// New creates an instance of Tidy and applies the given options to it. If an
+// option cannot be set, the instance is freed and the error returned names
+// the offending option.
+func New(opts ...Option) (*Tidy, error) {
+	t := &Tidy{parsed: -1}
 	t.tdoc = C.tidyCreate()
-	return t
+	C.tidySetErrorBuffer(t.tdoc, &t.errbuf) // Capture complaints about options too
+	t.startReporting()
+	if err := t.Apply(opts...); err != nil {
+		t.Free()
+		return nil, err
+	}
+	return t, nil
 }
 
 func (this *Tidy) Free() {
+	this.stopReporting()
+	this.releaseFormats()
 	C.tidyBufFree(&this.errbuf)
 	C.tidyRelease(this.tdoc)
 }
 
-func (this *Tidy) Tidy(htmlSource string) (string, error) {
-	input := C.CString(htmlSource)
-	defer C.free(unsafe.Pointer(input))
+// Tidy tidies htmlSource and returns the output as a UTF-8 string, whatever
+// the output encoding. The source is read in the input encoding. Use
+// TidyBytes for the output exactly as libtidy wrote it.
+//
+// Any overrides apply to this call only: the instance's options are restored
+// before Tidy returns, whether or not it succeeds.
+func (this *Tidy) Tidy(htmlSource string, overrides ...Option) (string, error) {
+	res, err := this.TidyBytes([]byte(htmlSource), overrides...)
+	if res == nil {
+		return "", err
+	}
+	return res.String(), err
+}
 
-	var output C.TidyBuffer
-	defer C.tidyBufFree(&output)
+// TidyBytes tidies input, which is read in the input encoding. The Result
+// holds the output both as libtidy wrote it, in the output encoding, and
+// decoded to UTF-8. Overrides work as for Tidy, and Stabilize is honoured.
+func (this *Tidy) TidyBytes(input []byte, overrides ...Option) (*Result, error) {
+	if len(overrides) > 0 {
+		saved, extra := this.saveConfig(), this.extra
+		defer func() {
+			this.restoreConfig(saved)
+			this.extra = extra
+		}()
+		if err := this.Apply(overrides...); err != nil {
+			return nil, err
+		}
+	}
+
+	rc, err := this.parse(input)
+	if err != nil {
+		return nil, err
+	}
+	return this.stabilize(this.save(rc))
+}
+
+// stabilize re-tidies res, the output of the first pass, until it comes out
+// the same. err, the Diagnostics and the SourceMap stay those of the first
+// pass, which are about the caller's input; the map is dropped if the output
+// changed. The document the passes leave behind is not the caller's, so none
+// is kept for Save.
+func (this *Tidy) stabilize(res *Result, err error) (*Result, error) {
+	if this.extra.stabilize == 0 || res == nil {
+		return res, err
+	}
+	saved, extra := this.saveConfig(), this.extra
+	diagnostics, sourceMap := this.diagnostics, this.sourceMap
+	defer func() {
+		this.restoreConfig(saved)
+		this.extra = extra
+		this.diagnostics = diagnostics
+		this.parsed, this.source = -1, nil
+	}()
+
+	// Read each pass's output the way it was written.
+	if _, encErr := this.InputEncoding(res.Encoding); encErr != nil {
+		return nil, encErr
+	}
+	this.extra.detectEncoding = false
+	this.extra.mapPositions = false
+
+	for pass := 0; pass < extra.stabilize; pass++ {
+		rc, parseErr := this.parse(res.Raw)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		next, nextErr := this.save(rc)
+		if next == nil {
+			return nil, nextErr
+		}
+		if bytes.Equal(next.Raw, res.Raw) {
+			if pass == 0 {
+				this.sourceMap = sourceMap
+			}
+			return res, err
+		}
+		res = next
+	}
+	return res, &UnstableError{extra.stabilize, err}
+}
+
+// parse reads input into the document, repairs it and runs the diagnostics,
+// returning libtidy's status: 0 if all went well, 1 for warnings, 2 for
+// errors and negative for a severe error, which is also returned as an error.
+func (this *Tidy) parse(input []byte) (C.int, error) {
+	defer this.useLanguage()()
+
+	var inbuf C.TidyBuffer
+	C.tidyBufInit(&inbuf)
+	defer C.tidyBufFree(&inbuf)
+	if len(input) > 0 {
+		C.tidyBufAppend(&inbuf, unsafe.Pointer(&input[0]), C.uint(len(input)))
+	}
+
+	this.parsed, this.source = -1, nil
+	this.releaseFormats()
+
+	if this.extra.detectEncoding {
+		restore, err := this.detectInputEncoding(input)
+		if err != nil {
+			return -1, err
+		}
+		defer restore()
+	}
 
 	var rc C.int = -1
 
+	C.tidyBufClear(&this.errbuf) // Report on this document only
+	this.diagnostics = nil
+
 	rc = C.tidySetErrorBuffer(this.tdoc, &this.errbuf) // Capture diagnostics
 
 	if rc >= 0 {
-		rc = C.tidyParseString(this.tdoc, (*C.tmbchar)(input)) // Parse the input
+		rc = C.tidyParseBuffer(this.tdoc, &inbuf) // Parse the input
 	}
 
 	if rc >= 0 {
-		rc = C.tidyCleanAndRepair(this.tdoc) // Tidy it up!	
+		rc = C.tidyCleanAndRepair(this.tdoc) // Tidy it up!
 	}
 
 	if rc >= 0 {
 		rc = C.tidyRunDiagnostics(this.tdoc) // Kvetch
 	}
+	this.collectDiagnostics()
+	if rc < 0 {
+		return rc, severeError(rc)
+	}
+	this.parsed, this.source, this.format = rc, append([]byte(nil), input...), this.outputFormat()
+	return rc, nil
+}
+
+// detectInputEncoding sets the input encoding to the one input declares, if
+// libtidy can read it. It is for one document only: the returned func puts
+// back the encoding the instance was configured with.
+func (this *Tidy) detectInputEncoding(input []byte) (func(), error) {
+	e, err := EncodingForLabel(DetectCharset(input, this.extra.contentType))
+	if err != nil {
+		return func() {}, nil // Nothing usable declared; read as configured.
+	}
+	configured := Encoding(C.tidyOptGetInt(this.tdoc, C.TidyInCharEncoding))
+	if _, err := this.InputEncoding(e); err != nil {
+		return nil, err
+	}
+	return func() { this.InputEncoding(configured) }, nil
+}
+
+// save writes out the parsed document. rc is the status parse returned.
+func (this *Tidy) save(rc C.int) (*Result, error) {
+	var output C.TidyBuffer
+	defer C.tidyBufFree(&output)
+	this.sourceMap = nil
 
 	if rc > 1 { // If error, force output.
-		if C.tidyOptSetBool(this.tdoc, C.TidyForceOutput, C.yes) == 0 {
+		ok, restore := this.forceOutput()
+		defer restore()
+		if !ok {
 			rc = -1
 		}
 	}
@@ -66,11 +219,123 @@ func (this *Tidy) Tidy(htmlSource string) (string, error) {
 	}
 
 	if rc >= 0 {
+		res := this.result(&output)
+		if this.extra.mapPositions {
+			this.sourceMap = this.mapSource(res.String())
+		}
 		if rc > 0 {
-			err := errors.New(C.GoStringN((*C.char)(unsafe.Pointer(this.errbuf.bp)), C.int(this.errbuf.size)))
-			return C.GoStringN((*C.char)(unsafe.Pointer(output.bp)), C.int(output.size)), err
+			return res, this.diagnosticsError()
+		}
+		return res, nil
+	}
+	return nil, severeError(rc)
+}
+
+// forceOutput turns on force-output, for a document with errors to be written
+// out. The function returned puts the option back as it was.
+func (this *Tidy) forceOutput() (bool, func()) {
+	restore := func() {}
+	if this.optGetInt(C.TidyForceOutput) == 0 {
+		restore = func() { C.gotidyOptSetBool(this.tdoc, C.TidyForceOutput, cBool(false)) }
+	}
+	return C.gotidyOptSetBool(this.tdoc, C.TidyForceOutput, cBool(true)) != 0, restore
+}
+
+// diagnosticsError returns what libtidy reported about the last document, or
+// nil if every message was muted.
+func (this *Tidy) diagnosticsError() error {
+	if this.errbuf.size == 0 {
+		return nil
+	}
+	return errors.New(C.GoStringN((*C.char)(unsafe.Pointer(this.errbuf.bp)), C.int(this.errbuf.size)))
+}
+
+func severeError(rc C.int) error {
+	return os.NewSyscallError(fmt.Sprintf("A severe error (%d) occurred.\n", int(rc)), errors.New(string(rc)))
+}
+
+// result wraps saved output in a Result. Encodings GoTidy can't decode itself
+// are dealt with by saving the document again, this time in UTF-8.
+func (this *Tidy) result(output *C.TidyBuffer) *Result {
+	res := &Result{
+		Raw:      C.GoBytes(unsafe.Pointer(output.bp), C.int(output.size)),
+		Encoding: Encoding(C.tidyOptGetInt(this.tdoc, C.TidyOutCharEncoding)),
+	}
+	res.Charset = res.Encoding.Charset()
+
+	if c := decoderFor(res.Encoding); c != nil {
+		res.text, _ = c.Decode(res.Raw)
+	} else {
+		saved := this.saveConfig()
+		this.OutputEncoding(Utf8)
+		this.OutputBom(False)
+		var utf8 C.TidyBuffer
+		C.tidySaveBuffer(this.tdoc, &utf8)
+		res.text = C.GoStringN((*C.char)(unsafe.Pointer(utf8.bp)), C.int(utf8.size))
+		C.tidyBufFree(&utf8)
+		this.restoreConfig(saved)
+	}
+	res.text = strings.TrimPrefix(res.text, "\uFEFF")
+	return res
+}
+
+// TidyCharset tidies input encoded in inCharset and returns the output encoded
+// in outCharset, or in UTF-8 if outCharset is empty. Charsets libtidy can read
+// or write are handed to it as they are; any other is converted to UTF-8
+// with the registered Charset and libtidy is run in UTF-8, and a charset the
+// document declares in a <meta> tag or XML declaration is changed to the
+// output's. The input and output encoding options are restored afterwards.
+// The report lists the characters that could not be converted.
+func (this *Tidy) TidyCharset(input []byte, inCharset, outCharset string) ([]byte, *TranscodeReport, error) {
+	report := &TranscodeReport{}
+
+	inEnc, err := EncodingForLabel(inCharset)
+	var in Charset
+	if err != nil {
+		if in, err = LookupCharset(inCharset); err != nil {
+			return nil, report, err
 		}
-		return C.GoStringN((*C.char)(unsafe.Pointer(output.bp)), C.int(output.size)), nil
+		inEnc = Utf8
+	}
+
+	outEnc := Utf8
+	var out Charset
+	if outCharset != "" {
+		if outEnc, err = EncodingForLabel(outCharset); err != nil {
+			if out, err = LookupCharset(outCharset); err != nil {
+				return nil, report, err
+			}
+			outEnc = Utf8
+		}
+	}
+
+	saved := this.saveConfig()
+	defer this.restoreConfig(saved)
+	detect := this.extra.detectEncoding
+	this.extra.detectEncoding = false
+	defer func() { this.extra.detectEncoding = detect }()
+
+	this.InputEncoding(inEnc)
+	this.OutputEncoding(outEnc)
+
+	if in != nil {
+		var text string
+		text, report.Input = in.Decode(input)
+		// The document no longer is in the charset it declares.
+		label := outCharset
+		if label == "" {
+			label = "utf-8"
+		}
+		input = relabelCharset([]byte(text), label)
+	}
+	res, err := this.TidyBytes(input)
+	if res == nil {
+		return nil, report, err
+	}
+	if out == nil {
+		return res.Raw, report, err
 	}
-	return "", os.NewSyscallError(fmt.Sprintf("A severe error (%d) occurred.\n", int(rc)), errors.New(string(rc)))
+	encoded, unmapped := out.Encode(res.String())
+	report.Output = unmapped
+	return encoded, report, err
 }
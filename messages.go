@@ -0,0 +1,214 @@
+package tidy
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Severity is the level libtidy reports a message at.
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Config
+	Access
+	Error
+	BadDocument
+	Fatal
+)
+
+var severityNames = []string{"Info", "Warning", "Config", "Access", "Error", "Document", "Fatal"}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+// MessageCode is the stable key tidy-html5 gives each kind of message. Unlike
+// the text, it does not change between releases or languages.
+type MessageCode string
+
+// Codes of the messages most often seen. Codes not listed here are reported
+// all the same; compare them with MessageCode("KEY").
+const (
+	AddedMissingCharset       MessageCode = "ADDED_MISSING_CHARSET"
+	AnchorNotUnique           MessageCode = "ANCHOR_NOT_UNIQUE"
+	AposUndefined             MessageCode = "APOS_UNDEFINED"
+	AttrValueNotLcase         MessageCode = "ATTR_VALUE_NOT_LCASE"
+	AttributeIsNotAllowed     MessageCode = "ATTRIBUTE_IS_NOT_ALLOWED"
+	AttributeValueReplaced    MessageCode = "ATTRIBUTE_VALUE_REPLACED"
+	BackslashInUri            MessageCode = "BACKSLASH_IN_URI"
+	BadAttributeValue         MessageCode = "BAD_ATTRIBUTE_VALUE"
+	BadAttributeValueReplaced MessageCode = "BAD_ATTRIBUTE_VALUE_REPLACED"
+	BadCdataContent           MessageCode = "BAD_CDATA_CONTENT"
+	BadSummaryHtml5           MessageCode = "BAD_SUMMARY_HTML5"
+	BadSurrogateLead          MessageCode = "BAD_SURROGATE_LEAD"
+	BadSurrogatePair          MessageCode = "BAD_SURROGATE_PAIR"
+	BadSurrogateTail          MessageCode = "BAD_SURROGATE_TAIL"
+	CantBeNested              MessageCode = "CANT_BE_NESTED"
+	CoerceToEndtag            MessageCode = "COERCE_TO_ENDTAG"
+	ContentAfterBody          MessageCode = "CONTENT_AFTER_BODY"
+	CustomTagDetected         MessageCode = "CUSTOM_TAG_DETECTED"
+	DiscardingUnexpected      MessageCode = "DISCARDING_UNEXPECTED"
+	DoctypeAfterTags          MessageCode = "DOCTYPE_AFTER_TAGS"
+	DuplicateFrameset         MessageCode = "DUPLICATE_FRAMESET"
+	ElementNotEmpty           MessageCode = "ELEMENT_NOT_EMPTY"
+	ElementVersMismatchError  MessageCode = "ELEMENT_VERS_MISMATCH_ERROR"
+	ElementVersMismatchWarn   MessageCode = "ELEMENT_VERS_MISMATCH_WARN"
+	EncodingMismatch          MessageCode = "ENCODING_MISMATCH"
+	EscapedIllegalUrl         MessageCode = "ESCAPED_ILLEGAL_URL"
+	FileCantOpen              MessageCode = "FILE_CANT_OPEN"
+	FixedBackslash            MessageCode = "FIXED_BACKSLASH"
+	FoundStyleInBody          MessageCode = "FOUND_STYLE_IN_BODY"
+	IdNameMismatch            MessageCode = "ID_NAME_MISMATCH"
+	IllegalNesting            MessageCode = "ILLEGAL_NESTING"
+	IllegalUriCodepoint       MessageCode = "ILLEGAL_URI_CODEPOINT"
+	IllegalUriReference       MessageCode = "ILLEGAL_URI_REFERENCE"
+	InsertingAutoAttribute    MessageCode = "INSERTING_AUTO_ATTRIBUTE"
+	InsertingTag              MessageCode = "INSERTING_TAG"
+	InvalidAttribute          MessageCode = "INVALID_ATTRIBUTE"
+	InvalidNcr                MessageCode = "INVALID_NCR"
+	InvalidSgmlChars          MessageCode = "INVALID_SGML_CHARS"
+	InvalidUtf8               MessageCode = "INVALID_UTF8"
+	InvalidUtf16              MessageCode = "INVALID_UTF16"
+	InvalidXmlId              MessageCode = "INVALID_XML_ID"
+	JoiningAttribute          MessageCode = "JOINING_ATTRIBUTE"
+	MalformedComment          MessageCode = "MALFORMED_COMMENT"
+	MalformedCommentDropping  MessageCode = "MALFORMED_COMMENT_DROPPING"
+	MalformedCommentEos       MessageCode = "MALFORMED_COMMENT_EOS"
+	MalformedCommentWarn      MessageCode = "MALFORMED_COMMENT_WARN"
+	MalformedDoctype          MessageCode = "MALFORMED_DOCTYPE"
+	MismatchedAttributeError  MessageCode = "MISMATCHED_ATTRIBUTE_ERROR"
+	MismatchedAttributeWarn   MessageCode = "MISMATCHED_ATTRIBUTE_WARN"
+	MissingAttrValue          MessageCode = "MISSING_ATTR_VALUE"
+	MissingAttribute          MessageCode = "MISSING_ATTRIBUTE"
+	MissingDoctype            MessageCode = "MISSING_DOCTYPE"
+	MissingEndtagBefore       MessageCode = "MISSING_ENDTAG_BEFORE"
+	MissingEndtagFor          MessageCode = "MISSING_ENDTAG_FOR"
+	MissingEndtagOptional     MessageCode = "MISSING_ENDTAG_OPTIONAL"
+	MissingImagemap           MessageCode = "MISSING_IMAGEMAP"
+	MissingQuotemark          MessageCode = "MISSING_QUOTEMARK"
+	MissingQuotemarkOpen      MessageCode = "MISSING_QUOTEMARK_OPEN"
+	MissingSemicolon          MessageCode = "MISSING_SEMICOLON"
+	MissingSemicolonNcr       MessageCode = "MISSING_SEMICOLON_NCR"
+	MissingStarttag           MessageCode = "MISSING_STARTTAG"
+	MissingTitleElement       MessageCode = "MISSING_TITLE_ELEMENT"
+	MovedStyleToHead          MessageCode = "MOVED_STYLE_TO_HEAD"
+	NestedEmphasis            MessageCode = "NESTED_EMPHASIS"
+	NestedQuotation           MessageCode = "NESTED_QUOTATION"
+	NewlineInUri              MessageCode = "NEWLINE_IN_URI"
+	NoframesContent           MessageCode = "NOFRAMES_CONTENT"
+	NonMatchingEndtag         MessageCode = "NON_MATCHING_ENDTAG"
+	ObsoleteElement           MessageCode = "OBSOLETE_ELEMENT"
+	OptionRemoved             MessageCode = "OPTION_REMOVED"
+	OptionRemovedApplied      MessageCode = "OPTION_REMOVED_APPLIED"
+	OptionRemovedUnapplied    MessageCode = "OPTION_REMOVED_UNAPPLIED"
+	PreviousLocation          MessageCode = "PREVIOUS_LOCATION"
+	ProprietaryAttrValue      MessageCode = "PROPRIETARY_ATTR_VALUE"
+	ProprietaryAttribute      MessageCode = "PROPRIETARY_ATTRIBUTE"
+	ProprietaryElement        MessageCode = "PROPRIETARY_ELEMENT"
+	RemovedHtml5              MessageCode = "REMOVED_HTML5"
+	RepeatedAttribute         MessageCode = "REPEATED_ATTRIBUTE"
+	ReplacingElement          MessageCode = "REPLACING_ELEMENT"
+	ReplacingUnexElement      MessageCode = "REPLACING_UNEX_ELEMENT"
+	SpacePrecedingXmldecl     MessageCode = "SPACE_PRECEDING_XMLDECL"
+	StringContentLooks        MessageCode = "STRING_CONTENT_LOOKS"
+	StringDoctypeGiven        MessageCode = "STRING_DOCTYPE_GIVEN"
+	StringNoSysid             MessageCode = "STRING_NO_SYSID"
+	SuspectedMissingQuote     MessageCode = "SUSPECTED_MISSING_QUOTE"
+	TagNotAllowedIn           MessageCode = "TAG_NOT_ALLOWED_IN"
+	TooManyElements           MessageCode = "TOO_MANY_ELEMENTS"
+	TooManyElementsIn         MessageCode = "TOO_MANY_ELEMENTS_IN"
+	TrimEmptyElement          MessageCode = "TRIM_EMPTY_ELEMENT"
+	UnescapedAmpersand        MessageCode = "UNESCAPED_AMPERSAND"
+	UnexpectedEndOfFile       MessageCode = "UNEXPECTED_END_OF_FILE"
+	UnexpectedEndOfFileAttr   MessageCode = "UNEXPECTED_END_OF_FILE_ATTR"
+	UnexpectedEndtag          MessageCode = "UNEXPECTED_ENDTAG"
+	UnexpectedEndtagErr       MessageCode = "UNEXPECTED_ENDTAG_ERR"
+	UnexpectedEndtagIn        MessageCode = "UNEXPECTED_ENDTAG_IN"
+	UnexpectedEqualsign       MessageCode = "UNEXPECTED_EQUALSIGN"
+	UnexpectedGt              MessageCode = "UNEXPECTED_GT"
+	UnexpectedQuotemark       MessageCode = "UNEXPECTED_QUOTEMARK"
+	UnknownElement            MessageCode = "UNKNOWN_ELEMENT"
+	UnknownElementLooksCustom MessageCode = "UNKNOWN_ELEMENT_LOOKS_CUSTOM"
+	UnknownEntity             MessageCode = "UNKNOWN_ENTITY"
+	UsingBrInplaceOf          MessageCode = "USING_BR_INPLACE_OF"
+	VendorSpecificChars       MessageCode = "VENDOR_SPECIFIC_CHARS"
+	WhiteInUri                MessageCode = "WHITE_IN_URI"
+	XmlDeclarationDetected    MessageCode = "XML_DECLARATION_DETECTED"
+	XmlIdSyntax               MessageCode = "XML_ID_SYNTAX"
+)
+
+// Diagnostic is one message libtidy reported about a document. Line and
+// Column are 0 for messages about the document as a whole. Code is empty when
+// the library doesn't supply message keys (see Capabilities.MessageCodes).
+type Diagnostic struct {
+	Code     MessageCode
+	Severity Severity
+	Line     int
+	Column   int
+	Message  string
+}
+
+// String formats d the way libtidy does.
+func (d Diagnostic) String() string {
+	if d.Line > 0 {
+		return fmt.Sprintf("line %d column %d - %v: %s", d.Line, d.Column, d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%v: %s", d.Severity, d.Message)
+}
+
+func codeSet(codes []MessageCode) map[MessageCode]bool {
+	if len(codes) == 0 {
+		return nil
+	}
+	set := make(map[MessageCode]bool, len(codes))
+	for _, c := range codes {
+		set[c] = true
+	}
+	return set
+}
+
+// reports says whether a message with the given code gets past Mute and Only.
+func (e *extraOptions) reports(code MessageCode) bool {
+	if e.muted[code] {
+		return false
+	}
+	return e.only == nil || e.only[code]
+}
+
+// Classic libtidy prefixes fatal messages with "panic" rather than "Fatal".
+var diagnosticLine = regexp.MustCompile(`^(?:line (\d+) column (\d+) - )?(Info|Warning|Config|Access|Error|Document|Fatal|panic): (.*)$`)
+
+// parseDiagnostics recovers diagnostics from libtidy's report, for libraries
+// without a message callback. Lines that aren't messages, such as the
+// summary, are skipped.
+func parseDiagnostics(report string) []Diagnostic {
+	var diags []Diagnostic
+	s := bufio.NewScanner(strings.NewReader(report))
+	for s.Scan() {
+		m := diagnosticLine.FindStringSubmatch(strings.TrimRight(s.Text(), "\r"))
+		if m == nil {
+			continue
+		}
+		d := Diagnostic{Message: m[4]}
+		d.Line, _ = strconv.Atoi(m[1])
+		d.Column, _ = strconv.Atoi(m[2])
+		if m[3] == "panic" {
+			m[3] = Fatal.String()
+		}
+		for i, name := range severityNames {
+			if name == m[3] {
+				d.Severity = Severity(i)
+			}
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
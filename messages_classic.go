@@ -0,0 +1,24 @@
+//go:build !tidyhtml5
+// +build !tidyhtml5
+
+package tidy
+
+/*
+#cgo CFLAGS: -I/usr/include/tidy
+#include "tidy_compat.h"
+*/
+import "C"
+import "unsafe"
+
+// Classic libtidy has no message callback, so diagnostics are read back out of
+// the error buffer and carry no codes.
+
+const messageCodes = false
+
+func (this *Tidy) startReporting() {}
+
+func (this *Tidy) stopReporting() {}
+
+func (this *Tidy) collectDiagnostics() {
+	this.diagnostics = parseDiagnostics(C.GoStringN((*C.char)(unsafe.Pointer(this.errbuf.bp)), C.int(this.errbuf.size)))
+}
@@ -0,0 +1,70 @@
+//go:build tidyhtml5
+// +build tidyhtml5
+
+package tidy
+
+/*
+#cgo CFLAGS: -I/usr/include/tidy
+#include <tidy.h>
+
+// Only declarations here: this file exports goTidyMessage.
+extern int goTidyMessage(TidyMessage tmessage);
+*/
+import "C"
+import (
+	"sync"
+	"unsafe"
+)
+
+// tidy-html5 hands each message to a callback, which files it under the Tidy
+// the document belongs to.
+
+const messageCodes = true
+
+var reporters = struct {
+	sync.Mutex
+	m map[C.TidyDoc]*Tidy
+}{m: make(map[C.TidyDoc]*Tidy)}
+
+func (this *Tidy) startReporting() {
+	reporters.Lock()
+	reporters.m[this.tdoc] = this
+	reporters.Unlock()
+	C.tidySetMessageCallback(this.tdoc, C.TidyMessageCallback(unsafe.Pointer(C.goTidyMessage)))
+}
+
+func (this *Tidy) stopReporting() {
+	reporters.Lock()
+	delete(reporters.m, this.tdoc)
+	reporters.Unlock()
+}
+
+// collectDiagnostics has nothing to do: the callback has seen every message.
+func (this *Tidy) collectDiagnostics() {}
+
+//export goTidyMessage
+func goTidyMessage(tmessage C.TidyMessage) C.int {
+	reporters.Lock()
+	this := reporters.m[C.tidyGetMessageDoc(tmessage)]
+	reporters.Unlock()
+	if this == nil {
+		return 1
+	}
+
+	level := Severity(C.tidyGetMessageLevel(tmessage) - C.TidyInfo)
+	if level > Fatal { // Summaries and footnotes, not messages
+		return 1
+	}
+	code := MessageCode(C.GoString(C.tidyGetMessageKey(tmessage)))
+	if !this.extra.reports(code) {
+		return 0 // Keep it out of the error buffer too
+	}
+	this.diagnostics = append(this.diagnostics, Diagnostic{
+		Code:     code,
+		Severity: level,
+		Line:     int(C.tidyGetMessageLine(tmessage)),
+		Column:   int(C.tidyGetMessageColumn(tmessage)),
+		Message:  C.GoString(C.tidyGetMessage(tmessage)),
+	})
+	return 1
+}
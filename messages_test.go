@@ -0,0 +1,46 @@
+package tidy
+
+import (
+	"testing"
+)
+
+func Test_ParseDiagnostics(t *testing.T) {
+	report := "line 1 column 1 - Warning: missing <!DOCTYPE> declaration\r\n" +
+		"line 3 column 5 - Error: <foo> is not recognized!\n" +
+		"Info: Document content looks like HTML5\n" +
+		"line 7 column 1 - panic: Can't create output file\n" +
+		"Tidy found 1 warning and 1 error!\n"
+	want := []Diagnostic{
+		{Severity: Warning, Line: 1, Column: 1, Message: "missing <!DOCTYPE> declaration"},
+		{Severity: Error, Line: 3, Column: 5, Message: "<foo> is not recognized!"},
+		{Severity: Info, Message: "Document content looks like HTML5"},
+		{Severity: Fatal, Line: 7, Column: 1, Message: "Can't create output file"},
+	}
+	got := parseDiagnostics(report)
+	if len(got) != len(want) {
+		t.Fatalf("parseDiagnostics found %d messages; want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("parseDiagnostics()[%d] = %+v; want %+v", i, got[i], want[i])
+		}
+	}
+	if s := got[0].String(); s != "line 1 column 1 - Warning: missing <!DOCTYPE> declaration" {
+		t.Errorf("Diagnostic.String() = %q", s)
+	}
+	if s := got[2].String(); s != "Info: Document content looks like HTML5" {
+		t.Errorf("Diagnostic.String() = %q", s)
+	}
+}
+
+func Test_MessageFilters(t *testing.T) {
+	var e extraOptions
+	if !e.reports(MissingEndtagFor) {
+		t.Errorf("With no filters every message must be reported")
+	}
+	e.muted = codeSet([]MessageCode{TrimEmptyElement})
+	e.only = codeSet([]MessageCode{MissingEndtagFor, TrimEmptyElement})
+	if !e.reports(MissingEndtagFor) || e.reports(TrimEmptyElement) || e.reports(MissingDoctype) {
+		t.Errorf("Mute must win over Only, and Only must keep out other codes")
+	}
+}
@@ -0,0 +1,143 @@
+package tidy
+
+import (
+	"errors"
+	"fmt"
+)
+
+// An Option is a single libtidy option and the value to give it. Options are
+// passed to New and Apply and are named after the libtidy configuration
+// option they set, e.g. "output-xml" or "indent".
+type Option struct {
+	name  string
+	value interface{}
+}
+
+// ErrUnsupportedOption is returned when setting an option the linked libtidy
+// does not have.
+var ErrUnsupportedOption = errors.New("Option is not supported by the linked libtidy")
+
+// extraOptions holds the options GoTidy implements itself rather than passing
+// on to libtidy.
+type extraOptions struct {
+	detectEncoding bool
+	contentType    string
+	backupSuffix   string
+	muted, only    map[MessageCode]bool
+	language       string
+	structuralDiff bool
+	mapPositions   bool
+	stabilize      int
+}
+
+// With returns an Option that sets the named libtidy option. The value must
+// have the type the corresponding setter takes. Options taking an Encoding,
+// AutoBool or one of the other named types also accept the libtidy name of
+// the value as a string, e.g. With("indent", "auto").
+func With(name string, value interface{}) Option {
+	return Option{name, value}
+}
+
+// Name returns the libtidy name of the option.
+func (o Option) Name() string {
+	return o.name
+}
+
+// Value returns the value the option is set to.
+func (o Option) Value() interface{} {
+	return o.value
+}
+
+func (o Option) String() string {
+	return fmt.Sprintf("%s: %v", o.name, o.value)
+}
+
+// See AddXmlDecl.
+func WithAddXmlDecl(val bool) Option {
+	return With("add-xml-decl", val)
+}
+
+// See Clean.
+func WithClean(val bool) Option {
+	return With("clean", val)
+}
+
+// See Doctype.
+func WithDoctype(val string) Option {
+	return With("doctype", val)
+}
+
+// See CharEncoding.
+func WithEncoding(val Encoding) Option {
+	return With("char-encoding", val)
+}
+
+// See ForceOutput.
+func WithForceOutput(val bool) Option {
+	return With("force-output", val)
+}
+
+// See Indent.
+func WithIndent(val AutoBool) Option {
+	return With("indent", val)
+}
+
+// See IndentSpaces.
+func WithIndentSpaces(val int) Option {
+	return With("indent-spaces", val)
+}
+
+// See InputEncoding.
+func WithInputEncoding(val Encoding) Option {
+	return With("input-encoding", val)
+}
+
+// See InputXml.
+func WithInputXml() Option {
+	return With("input-xml", true)
+}
+
+// See NumericEntities.
+func WithNumericEntities(val bool) Option {
+	return With("numeric-entities", val)
+}
+
+// See OutputEncoding.
+func WithOutputEncoding(val Encoding) Option {
+	return With("output-encoding", val)
+}
+
+// See OutputHtml.
+func WithOutputHtml() Option {
+	return With("output-html", true)
+}
+
+// See OutputXhtml.
+func WithOutputXhtml() Option {
+	return With("output-xhtml", true)
+}
+
+// See OutputXml.
+func WithOutputXml() Option {
+	return With("output-xml", true)
+}
+
+// See QuoteAmpersand.
+func WithQuoteAmpersand(val bool) Option {
+	return With("quote-ampersand", val)
+}
+
+// See ShowBodyOnly.
+func WithShowBodyOnly(val AutoBool) Option {
+	return With("show-body-only", val)
+}
+
+// See TidyMark.
+func WithTidyMark(val bool) Option {
+	return With("tidy-mark", val)
+}
+
+// See Wrap.
+func WithWrap(val int) Option {
+	return With("wrap", val)
+}
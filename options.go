@@ -3,20 +3,16 @@ package tidy
 /*
 #cgo CFLAGS: -I/usr/include/tidy
 #cgo LDFLAGS: -ltidy -L/usr/local/lib 
-#include <tidy.h>
-#include <buffio.h>
-#include <errno.h>
+#include "tidy_compat.h"
 */
 import "C"
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"unsafe"
 )
 
-const False int = 0
-const True int = 1
-const Auto int = 2
-
 // HTML, XHTML, XML Options
 
 // This option specifies if Tidy should add the XML declaration when outputting XML or XHTML. Note that if the input already includes an <?xml ... ?> declaration then this option will be ignored. If the encoding for the output is different from "ascii", one of the utf encodings or "raw", the declaration is always added as required by the XML standard.
@@ -33,7 +29,7 @@ func (this *Tidy) AddXmlSpace(val bool) (bool, error) {
 func (this *Tidy) AltText(val string) (bool, error) {
 	v := (*C.tmbchar)(C.CString(val))
 	defer C.free(unsafe.Pointer(v))
-	return this.optSetString(C.TidyXmlSpace, v)
+	return this.optSetString(C.TidyAltText, v)
 }
 
 // This option specifies if Tidy should change the parsing of processing instructions to require ?> as the terminator rather than >. This option is automatically set if the input is in XML.
@@ -70,6 +66,9 @@ func (this *Tidy) DecorateInferredUl(val bool) (bool, error) {
 //
 // If you specify the FPI for an XHTML document, Tidy will set the system identifier to an empty string. For an HTML document, Tidy adds a system identifier only if one was already present in order to preserve the processing mode of some browsers. Tidy leaves the DOCTYPE for generic XML documents unchanged. --doctype omit implies --numeric-entities yes. This option does not offer a validation of the document conformance.
 func (this *Tidy) Doctype(val string) (bool, error) {
+	if strings.HasPrefix(val, "-//") {
+		val = `"` + val + `"` // libtidy expects an FPI to be quoted.
+	}
 	v := (*C.tmbchar)(C.CString(val))
 	defer C.free(unsafe.Pointer(v))
 	return this.optSetString(C.TidyDoctype, v)
@@ -166,8 +165,8 @@ func (this *Tidy) LowerLiterals(val bool) (bool, error) {
 }
 
 // Can be used to modify behavior of -c (--clean yes) option. This option specifies if Tidy should merge nested <div> such as "<div><div>...</div></div>". If set to "auto", the attributes of the inner <div> are moved to the outer one. As well, nested <div> with ID attributes are not merged. If set to "yes", the attributes of the inner <div> are discarded with the exception of "class" and "style".
-func (this *Tidy) MergeDivs(val int) (bool, error) {
-	return this.optSetAutoBool(C.TidyMergeDivs, (C.ulong)(val))
+func (this *Tidy) MergeDivs(val AutoBool) (bool, error) {
+	return this.optSetAutoBool(C.TidyMergeDivs, val)
 }
 
 // This option specifies if Tidy should allow numeric character references.
@@ -238,16 +237,13 @@ func (this *Tidy) QuoteNbsp(val bool) (bool, error) {
 	return this.optSetBool(C.TidyQuoteNbsp, cBool(val))
 }
 
-const KeepFirst int = 0
-const KeepLast int = 1
-
 // This option specifies if Tidy should keep the first or last attribute, if an attribute is repeated, e.g. has two align attributes.
-func (this *Tidy) RepeatedAttributes(val int) (bool, error) {
+func (this *Tidy) RepeatedAttributes(val DuplicateAttrs) (bool, error) {
 	switch val {
 	case KeepFirst, KeepLast:
 		return this.optSetInt(C.TidyDuplicateAttrs, (C.ulong)(val))
 	}
-	return false, errors.New("Argument val int is out of range (0,1)")
+	return false, fmt.Errorf("DuplicateAttrs %d is out of range", val)
 }
 
 // This option specifies if Tidy should replace numeric values in color attributes by HTML/XHTML color names where defined, e.g. replace "#ffffff" with "white".
@@ -256,8 +252,8 @@ func (this *Tidy) ReplaceColor(val bool) (bool, error) {
 }
 
 // This option specifies if Tidy should print only the contents of the body tag as an HTML fragment. If set to "auto", this is performed only if the body tag has been inferred. Useful for incorporating existing whole pages as a portion of another page. This option has no effect if XML output is requested.
-func (this *Tidy) ShowBodyOnly(val int) (bool, error) {
-	return this.optSetAutoBool(C.TidyBodyOnly, (C.ulong)(val))
+func (this *Tidy) ShowBodyOnly(val AutoBool) (bool, error) {
+	return this.optSetAutoBool(C.TidyBodyOnly, val)
 }
 
 // This option specifies if Tidy should output attribute names in upper case. The default is no, which results in lower case attribute names, except for XML input, where the original case is preserved.
@@ -277,22 +273,20 @@ func (this *Tidy) Word2000(val bool) (bool, error) {
 
 // Diagnostics Options
 
-const TidyClassic int = 0
-const Priority1Checks int = 1
-const Priority2Checks int = 2
-const Priority3Checks int = 3
-
 // This option specifies what level of accessibility checking, if any, that Tidy should do. Level 0 is equivalent to Tidy Classic's accessibility checking. For more information on Tidy's accessibility checking, visit the Adaptive Technology Resource Centre at the University of Toronto.
-func (this *Tidy) AccessibilityCheck(val int) (bool, error) {
+func (this *Tidy) AccessibilityCheck(val AccessLevel) (bool, error) {
 	switch val {
 	case TidyClassic, Priority1Checks, Priority2Checks, Priority3Checks:
 		return this.optSetInt(C.TidyAccessibilityCheckLevel, (C.ulong)(val))
 	}
-	return false, errors.New("Argument val int is out of range (0,1,2,3)")
+	return false, fmt.Errorf("AccessLevel %d is out of range", val)
 }
 
 // This option specifies the number Tidy uses to determine if further errors should be shown. If set to 0, then no errors are shown.
 func (this *Tidy) ShowErrors(val int) (bool, error) {
+	if val < 0 {
+		return false, errors.New("Argument val int must not be negative")
+	}
 	return this.optSetInt(C.TidyShowErrors, (C.ulong)(val))
 }
 
@@ -309,8 +303,8 @@ func (this *Tidy) BreakBeforeBr(val bool) (bool, error) {
 }
 
 // This option specifies if Tidy should indent block-level tags. If set to "auto", this option causes Tidy to decide whether or not to indent the content of tags such as TITLE, H1-H6, LI, TD, TD, or P depending on whether or not the content includes a block-level element. You are advised to avoid setting indent to yes as this can expose layout bugs in some browsers.
-func (this *Tidy) Indent(val int) (bool, error) {
-	return this.optSetAutoBool(C.TidyIndentContent, (C.ulong)(val))
+func (this *Tidy) Indent(val AutoBool) (bool, error) {
+	return this.optSetAutoBool(C.TidyIndentContent, val)
 }
 
 // This option specifies if Tidy should begin each attribute on a new line.
@@ -320,6 +314,9 @@ func (this *Tidy) IndentAttributes(val bool) (bool, error) {
 
 // This option specifies the number of spaces Tidy uses to indent content, when indentation is enabled.
 func (this *Tidy) IndentSpaces(val int) (bool, error) {
+	if val < 0 {
+		return false, errors.New("Argument val int must not be negative")
+	}
 	return this.optSetInt(C.TidyIndentSpaces, (C.ulong)(val))
 }
 
@@ -333,9 +330,6 @@ func (this *Tidy) PunctuationWrap(val bool) (bool, error) {
 	return this.optSetBool(C.TidyPunctWrap, cBool(val))
 }
 
-const None int = 0
-const Alpha int = 1
-
 // Currently not used. Tidy Classic only.
 //func (this *Tidy) Split(val bool) (bool, error) {
 //	return this.optSetBool(C.TidyBurstSlides, cBool(val))
@@ -343,6 +337,9 @@ const Alpha int = 1
 
 // This option specifies the number of columns that Tidy uses between successive tab stops. It is used to map tabs to spaces when reading the input. Tidy never outputs tabs.
 func (this *Tidy) TabSize(val int) (bool, error) {
+	if val < 0 {
+		return false, errors.New("Argument val int must not be negative")
+	}
 	return this.optSetInt(C.TidyTabSize, (C.ulong)(val))
 }
 
@@ -353,6 +350,9 @@ func (this *Tidy) VerticalSpace(val bool) (bool, error) {
 
 // This option specifies the right margin Tidy uses for line wrapping. Tidy tries to wrap lines so that they do not exceed this length. Set wrap to zero if you want to disable line wrapping.
 func (this *Tidy) Wrap(val int) (bool, error) {
+	if val < 0 {
+		return false, errors.New("Argument val int must not be negative")
+	}
 	return this.optSetInt(C.TidyWrapLen, (C.ulong)(val))
 }
 
@@ -393,76 +393,72 @@ func (this *Tidy) AsciiChars(val bool) (bool, error) {
 	return this.optSetBool(C.TidyAsciiChars, cBool(val))
 }
 
-const Raw int = 0
-const Ascii int = 1
-const Latin0 int = 2
-const Latin1 int = 3
-const Utf8 int = 4
-const Iso2022 int = 5
-const Mac int = 6
-const Win1252 int = 7
-const Ibm858 int = 8
-const Utf16le int = 9
-const Utf16be int = 10
-const Utf16 int = 11
-const Big5 int = 12
-const Shiftjis int = 13
-
 // This option specifies the character encoding Tidy uses for both the input and output. For ascii, Tidy will accept Latin-1 (ISO-8859-1) character values, but will use entities for all characters whose value > 127. For raw, Tidy will output values above 127 without translating them into entities. For latin1, characters above 255 will be written as entities. For utf8, Tidy assumes that both input and output is encoded as UTF-8. You can use iso2022 for files encoded using the ISO-2022 family of encodings e.g. ISO-2022-JP. For mac and win1252, Tidy will accept vendor specific character values, but will use entities for all characters whose value > 127. For unsupported encodings, use an external utility to convert to and from UTF-8.
-func (this *Tidy) CharEncoding(val int) (bool, error) {
+func (this *Tidy) CharEncoding(val Encoding) (bool, error) {
 	switch val {
 	case Raw, Ascii, Latin0, Latin1, Utf8, Iso2022, Mac, Win1252, Ibm858, Utf16le, Utf16be, Utf16, Big5, Shiftjis:
 		return this.optSetInt(C.TidyCharEncoding, (C.ulong)(val))
 	}
-	return false, errors.New("Argument val int is out of range (0-13)")
+	return false, fmt.Errorf("Encoding %d is out of range", val)
 }
 
 // This option specifies the character encoding Tidy uses for the input. See char-encoding for more info.
-func (this *Tidy) InputEncoding(val int) (bool, error) {
+func (this *Tidy) InputEncoding(val Encoding) (bool, error) {
 	switch val {
 	case Raw, Ascii, Latin0, Latin1, Utf8, Iso2022, Mac, Win1252, Ibm858, Utf16le, Utf16be, Utf16, Big5, Shiftjis:
 		return this.optSetInt(C.TidyInCharEncoding, (C.ulong)(val))
 	}
-	return false, errors.New("Argument val int is out of range (0-13)")
+	return false, fmt.Errorf("Encoding %d is out of range", val)
 }
 
-// Currently not used, but this option specifies the language Tidy uses (for instance "en").
-//func (this *Tidy) Language(val string) (bool, error) {
-//	v := (*C.tmbchar)(C.CString(val))
-//	defer C.free(unsafe.Pointer(v))
-//	return this.optSetString(C.TidyLanguage, v)
-//}
+// InputEncodingName sets the input encoding from a charset label. See
+// EncodingForLabel and InputEncoding.
+func (this *Tidy) InputEncodingName(label string) (bool, error) {
+	e, err := EncodingForLabel(label)
+	if err != nil {
+		return false, err
+	}
+	return this.InputEncoding(e)
+}
 
-const LF int = 0
-const CRLF int = 1
-const CR int = 2
+// This option specifies if Tidy should work out the input encoding of each document itself before tidying it, using DetectCharset on the document and the Content-Type given by ContentType. If no charset is found, or it is one libtidy can't read, the input encoding is left as it is.
+func (this *Tidy) AutoDetectEncoding(val bool) (bool, error) {
+	this.extra.detectEncoding = val
+	return true, nil
+}
+
+// This option gives the HTTP Content-Type header the documents were served with, e.g. "text/html; charset=utf-8", for AutoDetectEncoding to take into account.
+func (this *Tidy) ContentType(val string) (bool, error) {
+	this.extra.contentType = val
+	return true, nil
+}
 
 // The default is appropriate to the current platform: CRLF on PC-DOS, MS-Windows and OS/2, CR on Classic Mac OS, and LF everywhere else (Unix and Linux).
-func (this *Tidy) Newline(val int) (bool, error) {
+func (this *Tidy) Newline(val NewlineMode) (bool, error) {
 	switch val {
 	case LF, CRLF, CR:
 		return this.optSetInt(C.TidyNewline, (C.ulong)(val))
 	}
-	return false, errors.New("Argument val int is out of range (0,1,2)")
+	return false, fmt.Errorf("NewlineMode %d is out of range", val)
 }
 
 // This option specifies if Tidy should write a Unicode Byte Order Mark character (BOM; also known as Zero Width No-Break Space; has value of U+FEFF) to the beginning of the output; only for UTF-8 and UTF-16 output encodings. If set to "auto", this option causes Tidy to write a BOM to the output only if a BOM was present at the beginning of the input. A BOM is always written for XML/XHTML output using UTF-16 output encodings.
-func (this *Tidy) OutputBom(val int) (bool, error) {
-	return this.optSetAutoBool(C.TidyOutputBOM, (C.ulong)(val))
+func (this *Tidy) OutputBom(val AutoBool) (bool, error) {
+	return this.optSetAutoBool(C.TidyOutputBOM, val)
 }
 
 // This option specifies the character encoding Tidy uses for the output. See char-encoding for more info. May only be different from input-encoding for Latin encodings (ascii, latin0, latin1, mac, win1252, ibm858).
-func (this *Tidy) OutputEncoding(val int) (bool, error) {
+func (this *Tidy) OutputEncoding(val Encoding) (bool, error) {
 	switch val {
 	case Raw, Ascii, Latin0, Latin1, Utf8, Iso2022, Mac, Win1252, Ibm858, Utf16le, Utf16be, Utf16, Big5, Shiftjis:
 		return this.optSetInt(C.TidyOutCharEncoding, (C.ulong)(val))
 	}
-	return false, errors.New("Argument val int is out of range (0-13)")
+	return false, fmt.Errorf("Encoding %d is out of range", val)
 }
 
 // Miscellaneous Options
 
-// This option specifies the error file Tidy uses for errors and warnings. Normally errors and warnings are output to "stderr".
+// This option specifies the error file Tidy uses for errors and warnings. Normally errors and warnings are output to "stderr". See TidyFile.
 func (this *Tidy) ErrorFile(val string) (bool, error) {
 	v := (*C.tmbchar)(C.CString(val))
 	defer C.free(unsafe.Pointer(v))
@@ -486,12 +482,12 @@ func (this *Tidy) GnuEmacsFile(val string) (bool, error) {
 	return this.optSetString(C.TidyEmacsFile, v)
 }
 
-// This option specifies if Tidy should keep the original modification time of files that Tidy modifies in place. The default is no. Setting the option to yes allows you to tidy files without causing these files to be uploaded to a web server when using a tool such as SiteCopy. Note this feature is not supported on some platforms.
+// This option specifies if Tidy should keep the original modification time of files that Tidy modifies in place. The default is no. Setting the option to yes allows you to tidy files without causing these files to be uploaded to a web server when using a tool such as SiteCopy. Note this feature is not supported on some platforms. See TidyFile.
 func (this *Tidy) KeepTime(val bool) (bool, error) {
 	return this.optSetBool(C.TidyKeepFileTimes, cBool(val))
 }
 
-// This option specifies the output file Tidy uses for markup. Normally markup is written to "stdout".
+// This option specifies the output file Tidy uses for markup. Normally markup is written to "stdout". See TidyFile.
 func (this *Tidy) OutputFile(val string) (bool, error) {
 	v := (*C.tmbchar)(C.CString(val))
 	defer C.free(unsafe.Pointer(v))
@@ -515,52 +511,87 @@ func (this *Tidy) TidyMark(val bool) (bool, error) {
 	return this.optSetBool(C.TidyMark, cBool(val))
 }
 
-// This option specifies if Tidy should write back the tidied markup to the same file it read from. You are advised to keep copies of important files before tidying them, as on rare occasions the result may not be what you expect.
+// This option specifies if Tidy should write back the tidied markup to the same file it read from. You are advised to keep copies of important files before tidying them, as on rare occasions the result may not be what you expect. See TidyFile.
 func (this *Tidy) WriteBack(val bool) (bool, error) {
 	return this.optSetBool(C.TidyWriteBack, cBool(val))
 }
 
-const Autobool_false C.ulong = 0
-const Autobool_true C.ulong = 1
-const Autobool_auto C.ulong = 2
+// This option specifies how many more times Tidy may tidy its own output until it stops changing, so that tidying the result again leaves it as it is. 0, the default, tidies once. Output still changing after that many passes is returned with an UnstableError. Tidy, TidyBytes, TidyFile and Diff honour it; Save doesn't.
+func (this *Tidy) Stabilize(val int) (bool, error) {
+	if val < 0 {
+		return false, errors.New("Argument val int must not be negative")
+	}
+	this.extra.stabilize = val
+	return true, nil
+}
 
-func (this *Tidy) optSetAutoBool(opt C.TidyOptionId, val C.ulong) (bool, error) {
+func (this *Tidy) optSetAutoBool(opt C.TidyOptionId, val AutoBool) (bool, error) {
 	switch val {
-	case Autobool_false, Autobool_true, Autobool_auto:
-		return this.optSetInt(opt, val)
+	case False, True, Auto:
+		return this.optSetInt(opt, (C.ulong)(val))
 	}
-	return false, errors.New("Argument val int is out of range (0,1,2)")
+	return false, fmt.Errorf("AutoBool %d is out of range", val)
 }
 
+// The optSet functions return true if libtidy accepted the value. If it
+// didn't, the error carries whatever libtidy reported about it.
+
 func (this *Tidy) optSetString(opt C.TidyOptionId, val *C.tmbchar) (bool, error) {
-	if C.tidyOptSetValue(this.tdoc, opt, val) == 1 {
-		return false, nil
+	mark := this.errbuf.size
+	if C.tidyOptSetValue(this.tdoc, opt, val) == 0 {
+		return false, this.optError(fmt.Sprintf("%q", C.GoString((*C.char)(val))), mark)
 	}
 	return true, nil
 }
 
 func (this *Tidy) optSetInt(opt C.TidyOptionId, val C.ulong) (bool, error) {
-	if C.tidyOptSetInt(this.tdoc, opt, val) == 1 {
-		return false, nil
+	mark := this.errbuf.size
+	if C.tidyOptSetInt(this.tdoc, opt, val) == 0 {
+		return false, this.optError(fmt.Sprint(uint64(val)), mark)
 	}
 	return true, nil
 }
 
-func (this *Tidy) optSetBool(opt C.TidyOptionId, val C.Bool) (bool, error) {
-	var rc C.int = -1
-	if C.tidyOptSetBool(this.tdoc, opt, val) == 1 {
-		rc = C.tidySetErrorBuffer(this.tdoc, &this.errbuf) // Capture diagnostics
-		if rc != 0 {
-			return false, errors.New(C.GoStringN((*C.char)(unsafe.Pointer(this.errbuf.bp)), C.int(this.errbuf.size)))
-		}
+func (this *Tidy) optSetBool(opt C.TidyOptionId, val C.int) (bool, error) {
+	mark := this.errbuf.size
+	if C.gotidyOptSetBool(this.tdoc, opt, val) == 0 {
+		return false, this.optError(fmt.Sprint(val != 0), mark)
 	}
 	return true, nil
 }
 
-func cBool(val bool) C.Bool {
-	var v uint32 = 0
+// optError describes a rejected option value, quoting anything libtidy wrote
+// to the error buffer after mark.
+func (this *Tidy) optError(val string, mark C.uint) error {
+	var msg string
+	if this.errbuf.size > mark {
+		start := unsafe.Pointer(uintptr(unsafe.Pointer(this.errbuf.bp)) + uintptr(mark))
+		msg = strings.TrimSpace(C.GoStringN((*C.char)(start), C.int(this.errbuf.size-mark)))
+	}
+	if msg == "" {
+		return fmt.Errorf("libtidy rejected value %s", val)
+	}
+	return fmt.Errorf("libtidy rejected value %s: %s", val, msg)
+}
+
+// optGetInt returns the value of an integer, enumerated or boolean option.
+func (this *Tidy) optGetInt(opt C.TidyOptionId) int {
+	return int(C.tidyOptGetInt(this.tdoc, opt))
+}
+
+// optGetString returns the value of a string option.
+func (this *Tidy) optGetString(opt C.TidyOptionId) string {
+	v := C.tidyOptGetValue(this.tdoc, opt)
+	if v == nil {
+		return ""
+	}
+	return C.GoString((*C.char)(v))
+}
+
+func cBool(val bool) C.int {
+	var v C.int = 0
 	if val {
 		v = 1
 	}
-	return C.Bool(v)
+	return v
 }
@@ -1,35 +1,70 @@
-// +build !darwin
 package tidy
 
+/*
+#cgo CFLAGS: -I/usr/include/tidy
+#cgo LDFLAGS: -ltidy -L/usr/local/lib
+#include "tidy_compat.h"
+*/
+import "C"
 import (
-	"C"
-	"errors"
+	"fmt"
+	"unsafe"
 )
 
+// Options not every libtidy knows about. tidyp, for one, lacks them, and the
+// libraries that do have them don't agree on their numbering, so they are
+// looked up by name when set rather than by a compile-time constant.
+
 // This option controls the deletion or addition of the name attribute in elements where it can serve as anchor. If set to "true", a name attribute, if not already existing, is added along an existing id attribute if the DTD allows it. If set to "false", any existing name attribute is removed if an id attribute exists or has been added.
 func (this *Tidy) AnchorAsName(val bool) (bool, error) {
-	// C.TidyAnchorAsName = 87 it doesn't exists in tidyp
-	return this.optSetBool(87, cBool(val))
+	opt, err := optionId("anchor-as-name")
+	if err != nil {
+		return false, err
+	}
+	return this.optSetBool(opt, cBool(val))
 }
 
 // Can be used to modify behavior of -c (--clean yes) option. This option specifies if Tidy should merge nested <span> such as "<span><span>...</span></span>". The algorithm is identical to the one used by --merge-divs.
-func (this *Tidy) MergeSpans(val int) (bool, error) {
-	// C.TidyMergeSpans = 86 it doesn't exists in tidyp
-	return this.optSetAutoBool(86, (C.ulong)(val))
+func (this *Tidy) MergeSpans(val AutoBool) (bool, error) {
+	opt, err := optionId("merge-spans")
+	if err != nil {
+		return false, err
+	}
+	return this.optSetAutoBool(opt, val)
 }
 
 // This option specifies if Tidy should preserve the well-formed entitites as found in the input.
 func (this *Tidy) PreserveEntities(val bool) (bool, error) {
-	// C.TidyPreserveEntities = 85 it doesn't exists in tidyp
-	return this.optSetBool(85, cBool(val))
+	opt, err := optionId("preserve-entities")
+	if err != nil {
+		return false, err
+	}
+	return this.optSetBool(opt, cBool(val))
 }
 
 // This option specifies that tidy should sort attributes within an element using the specified sort algorithm. If set to "alpha", the algorithm is an ascending alphabetic sort.
-func (this *Tidy) SortAttributes(val int) (bool, error) {
+func (this *Tidy) SortAttributes(val SortStrategy) (bool, error) {
+	opt, err := optionId("sort-attributes")
+	if err != nil {
+		return false, err
+	}
 	switch val {
 	case None, Alpha:
-		// C.TidySortAttributes = 84 it doesn't exists in tidyp
-		return this.optSetInt(84, (C.ulong)(val))
+		return this.optSetInt(opt, (C.ulong)(val))
+	}
+	return false, fmt.Errorf("SortStrategy %d is out of range", val)
+}
+
+// optionId looks up the ID the linked libtidy uses for the named option.
+// Libraries newer than the headers may hand out IDs beyond the compiled-in
+// N_TIDY_OPTIONS, so it is the library that decides whether an ID is valid;
+// tidyGetOption ignores its document.
+func optionId(name string) (C.TidyOptionId, error) {
+	n := C.CString(name)
+	defer C.free(unsafe.Pointer(n))
+	id := C.tidyOptGetIdForName((*C.tmbchar)(n))
+	if id == C.TidyUnknownOption || C.tidyGetOption(C.TidyDoc(nil), id) == nil {
+		return C.TidyUnknownOption, ErrUnsupportedOption
 	}
-	return false, errors.New("Argument val int is out of range (0,1)")
+	return id, nil
 }
@@ -0,0 +1,125 @@
+package tidy
+
+/*
+#cgo CFLAGS: -I/usr/include/tidy
+#cgo LDFLAGS: -ltidy -L/usr/local/lib
+#include "tidy_compat.h"
+*/
+import "C"
+import (
+	"unsafe"
+)
+
+// HTML5 Options
+//
+// These options only exist in tidy-html5. Like the extended options they are
+// looked up by name, so they return ErrUnsupportedOption when GoTidy is linked
+// against classic libtidy or tidyp.
+
+// This option specifies if Tidy should coerce a start tag into an end tag in cases where it looks like an end tag was probably intended; for example, given <span>foo <b>bar<b> baz</span> Tidy will output <span>foo <b>bar</b> baz</span>.
+func (this *Tidy) CoerceEndtags(val bool) (bool, error) {
+	opt, err := optionId("coerce-endtags")
+	if err != nil {
+		return false, err
+	}
+	return this.optSetBool(opt, cBool(val))
+}
+
+// This option specifies if Tidy should discard empty elements.
+func (this *Tidy) DropEmptyElements(val bool) (bool, error) {
+	opt, err := optionId("drop-empty-elements")
+	if err != nil {
+		return false, err
+	}
+	return this.optSetBool(opt, cBool(val))
+}
+
+// This option causes items that look like closing tags, like </g to be escaped to <\/g. Set this option to false if you do not want this.
+func (this *Tidy) EscapeScripts(val bool) (bool, error) {
+	opt, err := optionId("escape-scripts")
+	if err != nil {
+		return false, err
+	}
+	return this.optSetBool(opt, cBool(val))
+}
+
+// This option specifies if Tidy should indent with tabs instead of spaces, assuming indent is yes. Use the option indent-spaces to control the number of tabs output per level of indent. Note that when indent-with-tabs is enabled the default value of indent-spaces is reset to 1.
+func (this *Tidy) IndentWithTabs(val bool) (bool, error) {
+	opt, err := optionId("indent-with-tabs")
+	if err != nil {
+		return false, err
+	}
+	return this.optSetBool(opt, cBool(val))
+}
+
+// Use this option to prevent Tidy from displaying certain types of report output, for example, for conditions that you wish to ignore. This option takes a space or comma separated list of one or more keys indicating the message type to mute, e.g. "MISSING_ENDTAG_FOR TRIM_EMPTY_ELEMENT".
+func (this *Tidy) MuteMessages(val string) (bool, error) {
+	opt, err := optionId("mute")
+	if err != nil {
+		return false, err
+	}
+	v := (*C.tmbchar)(C.CString(val))
+	defer C.free(unsafe.Pointer(v))
+	return this.optSetString(opt, v)
+}
+
+// Mute stops messages with the given codes from being reported, either in
+// Diagnostics or in the error Tidy returns. It replaces the codes muted
+// before; call it with none to report everything again. Only libraries that
+// supply message codes can filter on them.
+func (this *Tidy) Mute(codes ...MessageCode) (bool, error) {
+	if !messageCodes {
+		return false, ErrUnsupportedOption
+	}
+	this.extra.muted = codeSet(codes)
+	return true, nil
+}
+
+// Only restricts the messages reported to those with the given codes. It
+// replaces the codes given before; call it with none to lift the restriction.
+// Muted codes stay muted even if listed here.
+func (this *Tidy) Only(codes ...MessageCode) (bool, error) {
+	if !messageCodes {
+		return false, ErrUnsupportedOption
+	}
+	this.extra.only = codeSet(codes)
+	return true, nil
+}
+
+// This option specifies if Tidy should omit optional start tags and end tags when generating output. Setting this option causes all tags for the <html>, <head>, and <body> elements to be omitted from output, as well as such end tags as </p>, </li>, </dt>, </dd>, </option>, </tr>, </td>, and </th>. This option is ignored for XML output.
+func (this *Tidy) OmitOptionalTags(val bool) (bool, error) {
+	opt, err := optionId("omit-optional-tags")
+	if err != nil {
+		return false, err
+	}
+	return this.optSetBool(opt, cBool(val))
+}
+
+// This option allows prioritizing the writing of attributes in tidied documents, allowing them to be written before the other attributes of an element. For example, you might specify that id and name are written before every other attribute. This option takes a space or comma separated list of attribute names.
+func (this *Tidy) PriorityAttributes(val string) (bool, error) {
+	opt, err := optionId("priority-attributes")
+	if err != nil {
+		return false, err
+	}
+	v := (*C.tmbchar)(C.CString(val))
+	defer C.free(unsafe.Pointer(v))
+	return this.optSetString(opt, v)
+}
+
+// This option specifies if Tidy should display info-level messages.
+func (this *Tidy) ShowInfo(val bool) (bool, error) {
+	opt, err := optionId("show-info")
+	if err != nil {
+		return false, err
+	}
+	return this.optSetBool(opt, cBool(val))
+}
+
+// This option specifies that Tidy should skip nested tags when parsing script and style data.
+func (this *Tidy) SkipNested(val bool) (bool, error) {
+	opt, err := optionId("skip-nested")
+	if err != nil {
+		return false, err
+	}
+	return this.optSetBool(opt, cBool(val))
+}
@@ -0,0 +1,276 @@
+//go:build cgo
+// +build cgo
+
+package tidy
+
+import (
+	"strings"
+	"testing"
+)
+
+// Every setter in the setters map (setters.go) must have an entry here.
+var setterTests = []struct {
+	name    string
+	valid   interface{}
+	invalid interface{} // nil if any value of the right type is accepted.
+}{
+	{"add-xml-decl", true, nil},
+	{"add-xml-space", true, nil},
+	{"alt-text", "Picture", nil},
+	{"assume-xml-procins", true, nil},
+	{"bare", true, nil},
+	{"clean", true, nil},
+	{"css-prefix", "tidy", nil},
+	{"decorate-inferred-ul", true, nil},
+	{"doctype", "strict", "bogus"},
+	{"drop-empty-paras", false, nil},
+	{"drop-proprietary-attributes", true, nil},
+	{"enclose-block-text", true, nil},
+	{"enclose-text", true, nil},
+	{"escape-cdata", true, nil},
+	{"fix-backslash", false, nil},
+	{"fix-bad-comments", false, nil},
+	{"fix-uri", false, nil},
+	{"hide-comments", true, nil},
+	{"indent-cdata", true, nil},
+	{"input-xml", true, nil},
+	{"join-classes", true, nil},
+	{"join-styles", false, nil},
+	{"literal-attributes", true, nil},
+	{"logical-emphasis", true, nil},
+	{"lower-literals", false, nil},
+	{"merge-divs", False, AutoBool(3)},
+	{"ncr", false, nil},
+	{"new-blocklevel-tags", "foo bar", nil},
+	{"new-empty-tags", "baz", nil},
+	{"new-inline-tags", "qux", nil},
+	{"new-pre-tags", "code2", nil},
+	{"numeric-entities", true, nil},
+	{"output-html", true, nil},
+	{"output-xhtml", true, nil},
+	{"output-xml", true, nil},
+	{"quote-ampersand", false, nil},
+	{"quote-marks", true, nil},
+	{"quote-nbsp", false, nil},
+	{"repeated-attributes", KeepFirst, DuplicateAttrs(2)},
+	{"replace-color", true, nil},
+	{"show-body-only", True, AutoBool(-1)},
+	{"uppercase-attributes", true, nil},
+	{"uppercase-tags", true, nil},
+	{"word-2000", true, nil},
+	{"accessibility-check", Priority2Checks, AccessLevel(4)},
+	{"show-errors", 3, -1},
+	{"show-warnings", false, nil},
+	{"break-before-br", true, nil},
+	{"indent", Auto, AutoBool(5)},
+	{"indent-attributes", true, nil},
+	{"indent-spaces", 4, -5},
+	{"markup", false, nil},
+	{"punctuation-wrap", true, nil},
+	{"tab-size", 4, -1},
+	{"vertical-space", true, nil},
+	{"wrap", 0, -1},
+	{"wrap-asp", false, nil},
+	{"wrap-attributes", true, nil},
+	{"wrap-jste", false, nil},
+	{"wrap-php", false, nil},
+	{"wrap-script-literals", true, nil},
+	{"wrap-sections", false, nil},
+	{"ascii-chars", true, nil},
+	{"char-encoding", Latin1, Encoding(14)},
+	{"input-encoding", Win1252, Encoding(-1)},
+	{"newline", CRLF, NewlineMode(3)},
+	{"output-bom", True, AutoBool(3)},
+	{"output-encoding", Utf16, Encoding(20)},
+	{"error-file", "errors.txt", nil},
+	{"force-output", true, nil},
+	{"gnu-emacs", true, nil},
+	{"gnu-emacs-file", "page.html", nil},
+	{"keep-time", true, nil},
+	{"output-file", "out.html", nil},
+	{"quiet", true, nil},
+	{"tidy-mark", false, nil},
+	{"write-back", true, nil},
+	{"anchor-as-name", false, nil},
+	{"merge-spans", True, AutoBool(3)},
+	{"preserve-entities", true, nil},
+	{"sort-attributes", Alpha, SortStrategy(2)},
+	{"coerce-endtags", false, nil},
+	{"drop-empty-elements", false, nil},
+	{"escape-scripts", false, nil},
+	{"indent-with-tabs", true, nil},
+	{"mute", "MISSING_ENDTAG_FOR", nil},
+	{"omit-optional-tags", true, nil},
+	{"priority-attributes", "id name", nil},
+	{"show-info", false, nil},
+	{"skip-nested", false, nil},
+}
+
+func Test_Setters(t *testing.T) {
+	tested := map[string]bool{}
+
+	for _, test := range setterTests {
+		tested[test.name] = true
+
+		tdy, _ := New()
+		opt, err := optionId(test.name)
+		if err == ErrUnsupportedOption {
+			tdy.Free()
+			continue
+		}
+
+		ok, err := setters[test.name](tdy, test.valid)
+		if !ok || err != nil {
+			t.Errorf("%s: valid value %v was rejected: %v", test.name, test.valid, err)
+		}
+
+		switch v := test.valid.(type) {
+		case bool:
+			if got := tdy.optGetInt(opt) != 0; got != v {
+				t.Errorf("%s: set to %v, reads back as %v", test.name, v, got)
+			}
+		case string:
+			if test.name == "doctype" {
+				break // Keywords set the doctype mode, not the string.
+			}
+			if got := tdy.optGetString(opt); !strings.Contains(got, strings.Fields(v)[0]) {
+				t.Errorf("%s: set to %q, reads back as %q", test.name, v, got)
+			}
+		default:
+			want := toInt(v)
+			if got := tdy.optGetInt(opt); got != want {
+				t.Errorf("%s: set to %v, reads back as %d", test.name, v, got)
+			}
+		}
+
+		if test.invalid != nil {
+			ok, err = setters[test.name](tdy, test.invalid)
+			if ok || err == nil {
+				t.Errorf("%s: invalid value %v was accepted", test.name, test.invalid)
+			}
+		}
+		tdy.Free()
+	}
+
+	for name := range setters {
+		if !tested[name] {
+			t.Errorf("%s: setter has no test", name)
+		}
+	}
+}
+
+// Setters that keep their option on the Go side, so Test_Setters can't read
+// them back through libtidy. Each must have an entry here.
+var goSetterTests = []struct {
+	name    string
+	valid   func(*Tidy) (bool, error)
+	took    func(*Tidy) bool          // Whether the valid value was stored.
+	invalid func(*Tidy) (bool, error) // nil if any value is accepted.
+}{
+	{"AutoDetectEncoding",
+		func(t *Tidy) (bool, error) { return t.AutoDetectEncoding(true) },
+		func(t *Tidy) bool { return t.extra.detectEncoding }, nil},
+	{"ContentType",
+		func(t *Tidy) (bool, error) { return t.ContentType("text/html; charset=utf-8") },
+		func(t *Tidy) bool { return t.extra.contentType == "text/html; charset=utf-8" }, nil},
+	{"InputEncodingName",
+		func(t *Tidy) (bool, error) { return t.InputEncodingName("windows-1252") },
+		func(t *Tidy) bool { opt, _ := optionId("input-encoding"); return Encoding(t.optGetInt(opt)) == Win1252 },
+		func(t *Tidy) (bool, error) { return t.InputEncodingName("bogus") }},
+	{"Stabilize",
+		func(t *Tidy) (bool, error) { return t.Stabilize(2) },
+		func(t *Tidy) bool { return t.extra.stabilize == 2 },
+		func(t *Tidy) (bool, error) { return t.Stabilize(-1) }},
+	{"MapPositions",
+		func(t *Tidy) (bool, error) { return t.MapPositions(true) },
+		func(t *Tidy) bool { return t.extra.mapPositions }, nil},
+	{"StructuralDiff",
+		func(t *Tidy) (bool, error) { return t.StructuralDiff(true) },
+		func(t *Tidy) bool { return t.extra.structuralDiff }, nil},
+	{"BackupSuffix",
+		func(t *Tidy) (bool, error) { return t.BackupSuffix(".orig") },
+		func(t *Tidy) bool { return t.extra.backupSuffix == ".orig" }, nil},
+	{"Language",
+		func(t *Tidy) (bool, error) { return t.Language("en") },
+		func(t *Tidy) bool { return t.extra.language == "en" },
+		func(t *Tidy) (bool, error) { return t.Language("xx") }},
+	{"Mute",
+		func(t *Tidy) (bool, error) { return t.Mute(MissingEndtagFor) },
+		func(t *Tidy) bool { return t.extra.muted[MissingEndtagFor] }, nil},
+	{"Only",
+		func(t *Tidy) (bool, error) { return t.Only(MissingEndtagFor) },
+		func(t *Tidy) bool { return t.extra.only[MissingEndtagFor] }, nil},
+}
+
+func Test_GoSetters(t *testing.T) {
+	for _, test := range goSetterTests {
+		tdy, _ := New()
+
+		ok, err := test.valid(tdy)
+		switch {
+		case err == ErrUnsupportedOption:
+			// Not available with the linked libtidy.
+		case !ok || err != nil:
+			t.Errorf("%s: valid value was rejected: %v", test.name, err)
+		case !test.took(tdy):
+			t.Errorf("%s: valid value was not stored", test.name)
+		case test.invalid != nil:
+			if ok, err = test.invalid(tdy); ok || err == nil {
+				t.Errorf("%s: invalid value was accepted", test.name)
+			}
+		}
+		tdy.Free()
+	}
+}
+
+func toInt(v interface{}) int {
+	switch v := v.(type) {
+	case AutoBool:
+		return int(v)
+	case DuplicateAttrs:
+		return int(v)
+	case AccessLevel:
+		return int(v)
+	case Encoding:
+		return int(v)
+	case NewlineMode:
+		return int(v)
+	case SortStrategy:
+		return int(v)
+	}
+	return v.(int)
+}
+
+func Test_SetterErrors(t *testing.T) {
+	tdy, _ := New()
+	defer tdy.Free()
+
+	ok, err := tdy.Doctype("bogus")
+	if ok || err == nil || !strings.Contains(err.Error(), "bogus") {
+		t.Errorf("The error must say which value libtidy rejected, got %v", err)
+	}
+
+	if ok, err = tdy.Doctype("-//ACME//DTD HTML 3.14159//EN"); !ok || err != nil {
+		t.Errorf("A formal public identifier must be accepted: %v", err)
+	}
+
+	if _, err = tdy.AccessibilityCheck(AccessLevel(7)); err == nil || err.Error() != "AccessLevel 7 is out of range" {
+		t.Errorf("The error must name the enum type and value, got %v", err)
+	}
+
+	if _, err = New(WithIndentSpaces(-5)); err == nil || !strings.Contains(err.Error(), "indent-spaces") {
+		t.Errorf("New must name the rejected option, got %v", err)
+	}
+}
+
+func Test_AltText(t *testing.T) {
+	tdy, _ := New()
+	defer tdy.Free()
+
+	tdy.AltText("A picture")
+	tdy.ShowBodyOnly(True)
+	output, _ := tdy.Tidy(`<img src="a.png">`)
+	if !strings.Contains(output, `alt="A picture"`) {
+		t.Errorf("AltText must set the alt-text option, got %q", output)
+	}
+}
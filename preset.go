@@ -0,0 +1,124 @@
+package tidy
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	presetsMu sync.RWMutex
+	presets   = map[string][]Option{
+		// Strict XHTML with presentational markup replaced by structure.
+		"xhtml-strict": {
+			WithOutputXhtml(),
+			WithDoctype("strict"),
+			WithClean(true),
+			With("logical-emphasis", true),
+			With("lower-literals", true),
+			WithQuoteAmpersand(true),
+		},
+		// The body of a document as a well-formed fragment, suitable for
+		// embedding in another page. This is what the gotidy binary uses.
+		// libtidy ignores show-body-only when writing XML, which Validate
+		// points out; the preset keeps it so that gotidy's output stays the
+		// same.
+		"html-fragment": {
+			WithOutputXml(),
+			WithAddXmlDecl(false),
+			WithQuoteAmpersand(true),
+			WithTidyMark(false),
+			WithEncoding(Utf8),
+			With("ascii-chars", true),
+			WithNumericEntities(true),
+			With("fix-uri", true),
+			With("drop-empty-paras", true),
+			With("drop-proprietary-attributes", true),
+			With("fix-backslash", true),
+			With("join-classes", true),
+			With("join-styles", true),
+			WithShowBodyOnly(True),
+		},
+		// Strip the cruft Microsoft Word leaves in documents saved as HTML.
+		"word-cleanup": {
+			With("word-2000", true),
+			WithClean(true),
+			With("bare", true),
+			With("drop-proprietary-attributes", true),
+		},
+		// As little whitespace and decoration as Tidy allows.
+		"minify": {
+			WithIndent(False),
+			WithWrap(0),
+			With("vertical-space", false),
+			With("break-before-br", false),
+			With("hide-comments", true),
+			WithTidyMark(false),
+		},
+		// Conservative HTML that survives mail clients and transports: a
+		// transitional doctype, inline styles left alone and ASCII output with
+		// everything else written as numeric entities.
+		"email-safe": {
+			WithOutputHtml(),
+			WithDoctype("loose"),
+			WithClean(false),
+			WithNumericEntities(true),
+			WithOutputEncoding(Ascii),
+			WithWrap(76),
+			WithTidyMark(false),
+		},
+		// Well-formed, indented XML.
+		"xml-pretty": {
+			WithInputXml(),
+			WithOutputXml(),
+			WithAddXmlDecl(true),
+			WithIndent(True),
+			WithIndentSpaces(2),
+			WithWrap(0),
+		},
+	}
+)
+
+// RegisterPreset makes a named set of options available to ApplyPreset and
+// Preset. It returns an error if a preset with that name already exists.
+func RegisterPreset(name string, opts ...Option) error {
+	presetsMu.Lock()
+	defer presetsMu.Unlock()
+	if _, ok := presets[name]; ok {
+		return fmt.Errorf("Preset %s is already registered", name)
+	}
+	presets[name] = append([]Option(nil), opts...)
+	return nil
+}
+
+// unregisterPreset removes a preset, so that tests can clean up after
+// RegisterPreset.
+func unregisterPreset(name string) {
+	presetsMu.Lock()
+	defer presetsMu.Unlock()
+	delete(presets, name)
+}
+
+// Preset returns the options that make up the named preset, so that they can
+// be passed to New along with any others.
+func Preset(name string) ([]Option, error) {
+	presetsMu.RLock()
+	defer presetsMu.RUnlock()
+	opts, ok := presets[name]
+	if !ok {
+		return nil, fmt.Errorf("Preset %s is not registered", name)
+	}
+	return append([]Option(nil), opts...), nil
+}
+
+// Presets returns the names of all registered presets in sorted order.
+func Presets() []string {
+	presetsMu.RLock()
+	defer presetsMu.RUnlock()
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
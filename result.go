@@ -0,0 +1,32 @@
+package tidy
+
+// Result is the output of tidying a document.
+type Result struct {
+	Raw      []byte   // The output as libtidy wrote it, e.g. for writing to a file.
+	Encoding Encoding // The output encoding Raw is in.
+	Charset  string   // The IANA name of Encoding, e.g. for a Content-Type header.
+
+	text string
+}
+
+// String returns the output decoded to UTF-8, without any byte order mark.
+// Unlike Raw, it is always valid UTF-8 and safe to range over.
+func (r *Result) String() string {
+	return r.text
+}
+
+// decoderFor returns the Charset that decodes output written in e, or nil if
+// there is none.
+func decoderFor(e Encoding) Charset {
+	switch e {
+	case Raw, Ascii, Utf8:
+		// Raw passes input bytes through untouched; most likely they are
+		// UTF-8, and anything else is replaced.
+		return utf8Charset{}
+	}
+	c, err := LookupCharset(e.Charset())
+	if err != nil {
+		return nil
+	}
+	return c
+}
@@ -0,0 +1,256 @@
+//go:build cgo
+// +build cgo
+
+package tidy
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Apply sets each of the given options in turn. It stops at the first option
+// that is unknown, has a value of the wrong type or is rejected, and returns
+// an error naming it.
+func (this *Tidy) Apply(opts ...Option) error {
+	for _, o := range opts {
+		set, ok := setters[o.name]
+		if !ok {
+			return fmt.Errorf("Option %s: unknown option", o.name)
+		}
+		if ok, err := set(this, o.value); err != nil {
+			return fmt.Errorf("Option %s: %s", o.name, err)
+		} else if !ok {
+			return fmt.Errorf("Option %s: value %v was not accepted", o.name, o.value)
+		}
+	}
+	return nil
+}
+
+// ApplyPreset sets every option of the named preset. Options not mentioned by
+// the preset keep their current values.
+func (this *Tidy) ApplyPreset(name string) error {
+	opts, err := Preset(name)
+	if err != nil {
+		return err
+	}
+	return this.Apply(opts...)
+}
+
+var setters = map[string]func(*Tidy, interface{}) (bool, error){
+	"add-xml-decl":                boolSetter((*Tidy).AddXmlDecl),
+	"add-xml-space":               boolSetter((*Tidy).AddXmlSpace),
+	"alt-text":                    stringSetter((*Tidy).AltText),
+	"assume-xml-procins":          boolSetter((*Tidy).AssumeXmlProcins),
+	"bare":                        boolSetter((*Tidy).Bare),
+	"clean":                       boolSetter((*Tidy).Clean),
+	"css-prefix":                  stringSetter((*Tidy).CssPrefix),
+	"decorate-inferred-ul":        boolSetter((*Tidy).DecorateInferredUl),
+	"doctype":                     stringSetter((*Tidy).Doctype),
+	"drop-empty-paras":            boolSetter((*Tidy).DropEmptyParas),
+	"drop-proprietary-attributes": boolSetter((*Tidy).DropProprietaryAttributes),
+	"enclose-block-text":          boolSetter((*Tidy).EncloseBlockText),
+	"enclose-text":                boolSetter((*Tidy).EncloseText),
+	"escape-cdata":                boolSetter((*Tidy).EscapeCdata),
+	"fix-backslash":               boolSetter((*Tidy).FixBackslash),
+	"fix-bad-comments":            boolSetter((*Tidy).FixBadComments),
+	"fix-uri":                     boolSetter((*Tidy).FixUri),
+	"hide-comments":               boolSetter((*Tidy).HideComments),
+	"indent-cdata":                boolSetter((*Tidy).IndentCdata),
+	"input-xml":                   boolSetter((*Tidy).InputXml),
+	"join-classes":                boolSetter((*Tidy).JoinClasses),
+	"join-styles":                 boolSetter((*Tidy).JoinStyles),
+	"literal-attributes":          boolSetter((*Tidy).LiteralAttributes),
+	"logical-emphasis":            boolSetter((*Tidy).LogicalEmphasis),
+	"lower-literals":              boolSetter((*Tidy).LowerLiterals),
+	"merge-divs":                  autoBoolSetter((*Tidy).MergeDivs),
+	"ncr":                         boolSetter((*Tidy).Ncr),
+	"new-blocklevel-tags":         stringSetter((*Tidy).NewBlocklevelTags),
+	"new-empty-tags":              stringSetter((*Tidy).NewEmptyTags),
+	"new-inline-tags":             stringSetter((*Tidy).NewInlineTags),
+	"new-pre-tags":                stringSetter((*Tidy).NewPreTags),
+	"numeric-entities":            boolSetter((*Tidy).NumericEntities),
+	"output-html":                 boolSetter((*Tidy).OutputHtml),
+	"output-xhtml":                boolSetter((*Tidy).OutputXhtml),
+	"output-xml":                  boolSetter((*Tidy).OutputXml),
+	"quote-ampersand":             boolSetter((*Tidy).QuoteAmpersand),
+	"quote-marks":                 boolSetter((*Tidy).QuoteMarks),
+	"quote-nbsp":                  boolSetter((*Tidy).QuoteNbsp),
+	"repeated-attributes":         duplicateAttrsSetter((*Tidy).RepeatedAttributes),
+	"replace-color":               boolSetter((*Tidy).ReplaceColor),
+	"show-body-only":              autoBoolSetter((*Tidy).ShowBodyOnly),
+	"uppercase-attributes":        boolSetter((*Tidy).UppercaseAttributes),
+	"uppercase-tags":              boolSetter((*Tidy).UppercaseTags),
+	"word-2000":                   boolSetter((*Tidy).Word2000),
+	"accessibility-check":         accessLevelSetter((*Tidy).AccessibilityCheck),
+	"show-errors":                 intSetter((*Tidy).ShowErrors),
+	"show-warnings":               boolSetter((*Tidy).ShowWarnings),
+	"break-before-br":             boolSetter((*Tidy).BreakBeforeBr),
+	"indent":                      autoBoolSetter((*Tidy).Indent),
+	"indent-attributes":           boolSetter((*Tidy).IndentAttributes),
+	"indent-spaces":               intSetter((*Tidy).IndentSpaces),
+	"markup":                      boolSetter((*Tidy).Markup),
+	"punctuation-wrap":            boolSetter((*Tidy).PunctuationWrap),
+	"tab-size":                    intSetter((*Tidy).TabSize),
+	"vertical-space":              boolSetter((*Tidy).VerticalSpace),
+	"wrap":                        intSetter((*Tidy).Wrap),
+	"wrap-asp":                    boolSetter((*Tidy).WrapAsp),
+	"wrap-attributes":             boolSetter((*Tidy).WrapAttributes),
+	"wrap-jste":                   boolSetter((*Tidy).WrapJste),
+	"wrap-php":                    boolSetter((*Tidy).WrapPhp),
+	"wrap-script-literals":        boolSetter((*Tidy).WrapScriptLiterals),
+	"wrap-sections":               boolSetter((*Tidy).WrapSections),
+	"ascii-chars":                 boolSetter((*Tidy).AsciiChars),
+	"char-encoding":               encodingSetter((*Tidy).CharEncoding),
+	"input-encoding":              encodingSetter((*Tidy).InputEncoding),
+	"newline":                     newlineSetter((*Tidy).Newline),
+	"output-bom":                  autoBoolSetter((*Tidy).OutputBom),
+	"output-encoding":             encodingSetter((*Tidy).OutputEncoding),
+	"error-file":                  stringSetter((*Tidy).ErrorFile),
+	"force-output":                boolSetter((*Tidy).ForceOutput),
+	"gnu-emacs":                   boolSetter((*Tidy).GnuEmacs),
+	"gnu-emacs-file":              stringSetter((*Tidy).GnuEmacsFile),
+	"keep-time":                   boolSetter((*Tidy).KeepTime),
+	"output-file":                 stringSetter((*Tidy).OutputFile),
+	"quiet":                       boolSetter((*Tidy).Quiet),
+	"tidy-mark":                   boolSetter((*Tidy).TidyMark),
+	"write-back":                  boolSetter((*Tidy).WriteBack),
+	"anchor-as-name":              boolSetter((*Tidy).AnchorAsName),
+	"merge-spans":                 autoBoolSetter((*Tidy).MergeSpans),
+	"preserve-entities":           boolSetter((*Tidy).PreserveEntities),
+	"sort-attributes":             sortStrategySetter((*Tidy).SortAttributes),
+	"coerce-endtags":              boolSetter((*Tidy).CoerceEndtags),
+	"drop-empty-elements":         boolSetter((*Tidy).DropEmptyElements),
+	"escape-scripts":              boolSetter((*Tidy).EscapeScripts),
+	"indent-with-tabs":            boolSetter((*Tidy).IndentWithTabs),
+	"mute":                        stringSetter((*Tidy).MuteMessages),
+	"omit-optional-tags":          boolSetter((*Tidy).OmitOptionalTags),
+	"priority-attributes":         stringSetter((*Tidy).PriorityAttributes),
+	"show-info":                   boolSetter((*Tidy).ShowInfo),
+	"skip-nested":                 boolSetter((*Tidy).SkipNested),
+}
+
+func boolSetter(set func(*Tidy, bool) (bool, error)) func(*Tidy, interface{}) (bool, error) {
+	return func(t *Tidy, val interface{}) (bool, error) {
+		v, ok := val.(bool)
+		if !ok {
+			return false, errors.New("Argument val must be a bool")
+		}
+		return set(t, v)
+	}
+}
+
+func intSetter(set func(*Tidy, int) (bool, error)) func(*Tidy, interface{}) (bool, error) {
+	return func(t *Tidy, val interface{}) (bool, error) {
+		v, ok := val.(int)
+		if !ok {
+			return false, errors.New("Argument val must be an int")
+		}
+		return set(t, v)
+	}
+}
+
+func stringSetter(set func(*Tidy, string) (bool, error)) func(*Tidy, interface{}) (bool, error) {
+	return func(t *Tidy, val interface{}) (bool, error) {
+		v, ok := val.(string)
+		if !ok {
+			return false, errors.New("Argument val must be a string")
+		}
+		return set(t, v)
+	}
+}
+
+func autoBoolSetter(set func(*Tidy, AutoBool) (bool, error)) func(*Tidy, interface{}) (bool, error) {
+	return func(t *Tidy, val interface{}) (bool, error) {
+		switch v := val.(type) {
+		case AutoBool:
+			return set(t, v)
+		case string:
+			a, err := ParseAutoBool(v)
+			if err != nil {
+				return false, err
+			}
+			return set(t, a)
+		}
+		return false, errors.New("Argument val must be an AutoBool")
+	}
+}
+
+func encodingSetter(set func(*Tidy, Encoding) (bool, error)) func(*Tidy, interface{}) (bool, error) {
+	return func(t *Tidy, val interface{}) (bool, error) {
+		switch v := val.(type) {
+		case Encoding:
+			return set(t, v)
+		case string:
+			e, err := ParseEncoding(v)
+			if err != nil {
+				return false, err
+			}
+			return set(t, e)
+		}
+		return false, errors.New("Argument val must be an Encoding")
+	}
+}
+
+func newlineSetter(set func(*Tidy, NewlineMode) (bool, error)) func(*Tidy, interface{}) (bool, error) {
+	return func(t *Tidy, val interface{}) (bool, error) {
+		switch v := val.(type) {
+		case NewlineMode:
+			return set(t, v)
+		case string:
+			n, err := ParseNewlineMode(v)
+			if err != nil {
+				return false, err
+			}
+			return set(t, n)
+		}
+		return false, errors.New("Argument val must be a NewlineMode")
+	}
+}
+
+func duplicateAttrsSetter(set func(*Tidy, DuplicateAttrs) (bool, error)) func(*Tidy, interface{}) (bool, error) {
+	return func(t *Tidy, val interface{}) (bool, error) {
+		switch v := val.(type) {
+		case DuplicateAttrs:
+			return set(t, v)
+		case string:
+			d, err := ParseDuplicateAttrs(v)
+			if err != nil {
+				return false, err
+			}
+			return set(t, d)
+		}
+		return false, errors.New("Argument val must be a DuplicateAttrs")
+	}
+}
+
+func sortStrategySetter(set func(*Tidy, SortStrategy) (bool, error)) func(*Tidy, interface{}) (bool, error) {
+	return func(t *Tidy, val interface{}) (bool, error) {
+		switch v := val.(type) {
+		case SortStrategy:
+			return set(t, v)
+		case string:
+			st, err := ParseSortStrategy(v)
+			if err != nil {
+				return false, err
+			}
+			return set(t, st)
+		}
+		return false, errors.New("Argument val must be a SortStrategy")
+	}
+}
+
+func accessLevelSetter(set func(*Tidy, AccessLevel) (bool, error)) func(*Tidy, interface{}) (bool, error) {
+	return func(t *Tidy, val interface{}) (bool, error) {
+		switch v := val.(type) {
+		case AccessLevel:
+			return set(t, v)
+		case string:
+			a, err := ParseAccessLevel(v)
+			if err != nil {
+				return false, err
+			}
+			return set(t, a)
+		}
+		return false, errors.New("Argument val must be an AccessLevel")
+	}
+}
@@ -0,0 +1,144 @@
+package tidy
+
+/*
+#cgo CFLAGS: -I/usr/include/tidy
+#cgo LDFLAGS: -ltidy -L/usr/local/lib
+#include "tidy_compat.h"
+*/
+import "C"
+import (
+	"sort"
+	"strings"
+)
+
+// SourceMap translates positions in tidied output back to the input. It maps
+// each element's start tag in the output to where the element started in the
+// input; anything else maps to the element before it.
+type SourceMap struct {
+	spans      []mapSpan
+	lineStarts []int // Offset of each output line.
+}
+
+type mapSpan struct {
+	offset       int // Where the start tag begins in the output.
+	line, column int // Where the element began in the input, from 1.
+}
+
+// sourceNode is an element as libtidy parsed it.
+type sourceNode struct {
+	name         string
+	line, column int
+}
+
+// This option specifies if Tidy should map the output back to the input as it saves it, for SourceMap to return.
+func (this *Tidy) MapPositions(val bool) (bool, error) {
+	this.extra.mapPositions = val
+	return true, nil
+}
+
+// SourceMap returns the map for the output last saved, or nil if
+// MapPositions was off.
+func (this *Tidy) SourceMap() *SourceMap {
+	return this.sourceMap
+}
+
+// Offset returns the input line and column that produced the output at the
+// given byte offset into Result.String(). ok is false if the offset comes
+// before the first element mapped.
+func (m *SourceMap) Offset(offset int) (line, column int, ok bool) {
+	i := sort.Search(len(m.spans), func(i int) bool { return m.spans[i].offset > offset }) - 1
+	if i < 0 {
+		return 0, 0, false
+	}
+	return m.spans[i].line, m.spans[i].column, true
+}
+
+// Position is like Offset but takes an output line and column, counting from
+// 1 and in bytes.
+func (m *SourceMap) Position(line, column int) (int, int, bool) {
+	if line < 1 || line > len(m.lineStarts) {
+		return 0, 0, false
+	}
+	return m.Offset(m.lineStarts[line-1] + column - 1)
+}
+
+// mapSource walks the parsed document for newSourceMap.
+func (this *Tidy) mapSource(output string) *SourceMap {
+	var nodes []sourceNode
+	var walk func(C.TidyNode)
+	walk = func(node C.TidyNode) {
+		for ; node != nil; node = C.tidyGetNext(node) {
+			switch C.tidyNodeGetType(node) {
+			case C.TidyNode_Start, C.TidyNode_StartEnd:
+				nodes = append(nodes, sourceNode{
+					name:   C.GoString(C.tidyNodeGetName(node)),
+					line:   int(C.tidyNodeLine(node)),
+					column: int(C.tidyNodeColumn(node)),
+				})
+			}
+			walk(C.tidyGetChild(node))
+		}
+	}
+	if body := C.tidyGetBody(this.tdoc); body != nil && findTag(asciiLower(output), 0, "body") < 0 {
+		walk(C.tidyGetChild(body)) // Only the body's content was written
+	} else {
+		walk(C.tidyGetChild(C.tidyGetRoot(this.tdoc)))
+	}
+	return newSourceMap(output, nodes)
+}
+
+// newSourceMap finds the start tags of nodes, which are in document order, in
+// output. Elements that weren't written out, such as omitted optional tags,
+// or that were added by libtidy and have no input position, are skipped.
+func newSourceMap(output string, nodes []sourceNode) *SourceMap {
+	m := &SourceMap{lineStarts: []int{0}}
+	for i := 0; i < len(output); i++ {
+		if output[i] == '\n' {
+			m.lineStarts = append(m.lineStarts, i+1)
+		}
+	}
+
+	lower := asciiLower(output)
+	pos := 0
+	for _, n := range nodes {
+		at := findTag(lower, pos, asciiLower(n.name))
+		if at < 0 {
+			continue
+		}
+		pos = at + 1
+		if n.line > 0 {
+			m.spans = append(m.spans, mapSpan{at, n.line, n.column})
+		}
+	}
+	return m
+}
+
+// findTag returns the offset of the first start tag for name in the
+// lower-cased output at or after from, or -1 if there is none.
+func findTag(lower string, from int, name string) int {
+	for from < len(lower) {
+		i := strings.Index(lower[from:], "<"+name)
+		if i < 0 {
+			break
+		}
+		at := from + i
+		end := at + 1 + len(name)
+		if end == len(lower) || strings.IndexByte(" \t\r\n/>", lower[end]) >= 0 {
+			return at
+		}
+		from = at + 1
+	}
+	return -1
+}
+
+// asciiLower lower-cases the ASCII letters in s only, so that offsets into it
+// are offsets into s.
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,41 @@
+//go:build cgo
+// +build cgo
+
+package tidy
+
+import (
+	"testing"
+)
+
+func Test_NewSourceMap(t *testing.T) {
+	output := "<html>\n<body>\n<p>One</p>\n<P class=x>Two <b>three</b></P>\n</body>\n</html>\n"
+	nodes := []sourceNode{
+		{"html", 0, 0}, // Inserted by libtidy
+		{"head", 0, 0}, // Not written out
+		{"body", 0, 0},
+		{"p", 1, 1},
+		{"p", 3, 2},
+		{"b", 3, 9},
+	}
+	m := newSourceMap(output, nodes)
+
+	tests := []struct {
+		line, column         int
+		wantLine, wantColumn int
+		ok                   bool
+	}{
+		{1, 1, 0, 0, false},
+		{3, 1, 1, 1, true},
+		{3, 5, 1, 1, true},
+		{4, 1, 3, 2, true},
+		{4, 16, 3, 9, true},
+		{5, 1, 3, 9, true},
+		{9, 1, 0, 0, false},
+	}
+	for _, test := range tests {
+		line, column, ok := m.Position(test.line, test.column)
+		if line != test.wantLine || column != test.wantColumn || ok != test.ok {
+			t.Errorf("Position(%d, %d) = %d, %d, %v; want %d, %d, %v", test.line, test.column, line, column, ok, test.wantLine, test.wantColumn, test.ok)
+		}
+	}
+}
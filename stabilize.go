@@ -0,0 +1,20 @@
+package tidy
+
+import (
+	"fmt"
+)
+
+// UnstableError is returned with the output of the last pass when Stabilize
+// runs out of passes while the output is still changing.
+type UnstableError struct {
+	Passes int
+	Err    error // What libtidy reported about the input, if anything.
+}
+
+func (e *UnstableError) Error() string {
+	msg := fmt.Sprintf("Output still changing after %d passes", e.Passes)
+	if e.Err != nil {
+		msg += "\n" + e.Err.Error()
+	}
+	return msg
+}
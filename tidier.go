@@ -0,0 +1,14 @@
+package tidy
+
+// Tidier is what code using GoTidy needs from a Tidy: tidying documents,
+// setting options and reading what was reported. *Tidy implements it, and so
+// does Fake, which needs neither cgo nor libtidy; accept a Tidier rather than
+// a *Tidy to test such code with a Fake.
+type Tidier interface {
+	Tidy(htmlSource string, overrides ...Option) (string, error)
+	TidyBytes(input []byte, overrides ...Option) (*Result, error)
+	Lint(input []byte) (Report, error)
+	Apply(opts ...Option) error
+	Diagnostics() []Diagnostic
+	Free()
+}
@@ -0,0 +1,50 @@
+/*
+ * Papers over the differences between the classic libtidy/tidyp headers and
+ * those of tidy-html5. Build with the tidyhtml5 tag to use the latter.
+ */
+#ifndef GOTIDY_COMPAT_H
+#define GOTIDY_COMPAT_H
+
+#include <stdlib.h>
+#include <errno.h>
+#include <dlfcn.h>
+#include <tidy.h>
+#ifdef GOTIDY_HTML5
+#include <tidybuffio.h>
+#else
+#include <buffio.h>
+#endif
+
+/* Bool in classic libtidy, TidyBool in tidy-html5; an int on the Go side. */
+static int gotidyOptSetBool(TidyDoc tdoc, TidyOptionId opt, int val) {
+	return tidyOptSetBool(tdoc, opt, val != 0);
+}
+
+/*
+ * tidyLibraryVersion only exists in tidy-html5. Built against older headers,
+ * look for it in the library actually loaded, which may have been upgraded
+ * since.
+ */
+static ctmbstr gotidyLibraryVersion(void) {
+#ifdef GOTIDY_HTML5
+	return tidyLibraryVersion();
+#else
+	void *self = dlopen(NULL, RTLD_LAZY);
+	ctmbstr (*version)(void) = NULL;
+	if (self != NULL) {
+		version = (ctmbstr (*)(void))dlsym(self, "tidyLibraryVersion");
+		dlclose(self);
+	}
+	return version != NULL ? version() : NULL;
+#endif
+}
+
+static int gotidyIsHtml5(void) {
+#ifdef GOTIDY_HTML5
+	return 1;
+#else
+	return 0;
+#endif
+}
+
+#endif
@@ -1,14 +1,22 @@
+//go:build cgo
+// +build cgo
+
 package tidy
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 )
 
 var corruptedHtml string = "<title id='bob' class='frank'>Hello, 世界</title><p>Foo!"
 
 func Test_Tidy(t *testing.T) {
-	tdy := New()
+	tdy, _ := New()
 	defer tdy.Free()
 
 	output, _ := tdy.Tidy(corruptedHtml)
@@ -19,7 +27,7 @@ func Test_Tidy(t *testing.T) {
 }
 
 func Test_AddXmlDecl(t *testing.T) {
-	tdy := New()
+	tdy, _ := New()
 	defer tdy.Free()
 
 	var output string
@@ -40,7 +48,7 @@ func Test_AddXmlDecl(t *testing.T) {
 }
 
 func Test_TidyMark(t *testing.T) {
-	tdy := New()
+	tdy, _ := New()
 	defer tdy.Free()
 
 	var output string
@@ -60,7 +68,7 @@ func Test_TidyMark(t *testing.T) {
 }
 
 func Test_Multibyte(t *testing.T) {
-	tdy := New()
+	tdy, _ := New()
 	defer tdy.Free()
 
 	var output string
@@ -73,3 +81,712 @@ func Test_Multibyte(t *testing.T) {
 		t.Errorf("The output is not in UTF-8 or unicode symbols were encoded")
 	}
 }
+
+func Test_SnapshotRestore(t *testing.T) {
+	tdy, _ := New()
+	defer tdy.Free()
+
+	var output string
+
+	tdy.TidyMark(false)
+	tdy.Snapshot()
+
+	tdy.TidyMark(true)
+	tdy.Restore()
+	output, _ = tdy.Tidy(corruptedHtml)
+	if strings.Contains(output, "HTML Tidy for") {
+		t.Errorf("Restore must roll back options changed after Snapshot")
+	}
+
+	tdy.Reset()
+	output, _ = tdy.Tidy(corruptedHtml)
+	if !strings.Contains(output, "HTML Tidy for") {
+		t.Errorf("Reset must restore the default options")
+	}
+}
+
+func Test_Clone(t *testing.T) {
+	tdy, _ := New()
+	defer tdy.Free()
+
+	tdy.TidyMark(false)
+
+	clone := tdy.Clone()
+	defer clone.Free()
+
+	output, _ := clone.Tidy(corruptedHtml)
+	if strings.Contains(output, "HTML Tidy for") {
+		t.Errorf("Clone must copy the options of the original")
+	}
+
+	clone.TidyMark(true)
+	output, _ = tdy.Tidy(corruptedHtml)
+	if strings.Contains(output, "HTML Tidy for") {
+		t.Errorf("Changing the clone must not affect the original")
+	}
+}
+
+func Test_NewWithOptions(t *testing.T) {
+	tdy, err := New(WithOutputXml(), WithAddXmlDecl(true), WithTidyMark(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tdy.Free()
+
+	output, _ := tdy.Tidy(corruptedHtml)
+	if !strings.HasPrefix(output, "<?xml") {
+		t.Errorf("Options passed to New were not applied")
+	}
+
+	if _, err = New(With("no-such-option", true)); err == nil {
+		t.Errorf("An unknown option must be reported")
+	}
+	if _, err = New(With("wrap", "wide")); err == nil {
+		t.Errorf("An option value of the wrong type must be reported")
+	}
+}
+
+func Test_Presets(t *testing.T) {
+	for _, name := range Presets() {
+		opts, err := Preset(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tdy, err := New(opts...)
+		if err != nil {
+			t.Errorf("Preset %s: %s", name, err)
+			continue
+		}
+		tdy.Free()
+	}
+
+	if err := RegisterPreset("test-no-mark", WithTidyMark(false)); err != nil {
+		t.Fatal(err)
+	}
+	defer unregisterPreset("test-no-mark")
+	if err := RegisterPreset("test-no-mark"); err == nil {
+		t.Errorf("Registering a preset twice must fail")
+	}
+
+	tdy, _ := New()
+	defer tdy.Free()
+
+	tdy.ApplyPreset("test-no-mark")
+	output, _ := tdy.Tidy(corruptedHtml)
+	if strings.Contains(output, "HTML Tidy for") {
+		t.Errorf("Options of a registered preset were not applied")
+	}
+}
+
+func Test_ExtendedOptions(t *testing.T) {
+	tdy, _ := New()
+	defer tdy.Free()
+
+	if _, err := tdy.AnchorAsName(true); err != nil && err != ErrUnsupportedOption {
+		t.Errorf("AnchorAsName: %s", err)
+	}
+	if _, err := tdy.PreserveEntities(true); err != nil && err != ErrUnsupportedOption {
+		t.Errorf("PreserveEntities: %s", err)
+	}
+	if _, err := tdy.MergeSpans(Auto); err != nil && err != ErrUnsupportedOption {
+		t.Errorf("MergeSpans: %s", err)
+	}
+	if _, err := tdy.SortAttributes(Alpha); err != nil && err != ErrUnsupportedOption {
+		t.Errorf("SortAttributes: %s", err)
+	}
+	if _, err := optionId("no-such-option"); err != ErrUnsupportedOption {
+		t.Errorf("An unknown option name must give ErrUnsupportedOption")
+	}
+}
+
+func Test_Html5Options(t *testing.T) {
+	tdy, _ := New()
+	defer tdy.Free()
+
+	if _, err := tdy.DropEmptyElements(false); err == ErrUnsupportedOption {
+		t.Skip("Linked libtidy is not tidy-html5")
+	}
+
+	var output string
+
+	tdy.ShowBodyOnly(True)
+	tdy.OmitOptionalTags(true)
+	output, _ = tdy.Tidy("<section><p>One<p>Two</section>")
+	if strings.Contains(output, "</p>") {
+		t.Errorf("Optional end tags must be omitted")
+	}
+	if !strings.Contains(output, "<section>") {
+		t.Errorf("HTML5 elements must be kept")
+	}
+}
+
+func Test_Capabilities(t *testing.T) {
+	c := LibraryCapabilities()
+
+	if c.ReleaseDate == "" {
+		t.Errorf("Release date of the linked libtidy is missing")
+	}
+	if c.Library == TidyHtml5 && c.Version == "" {
+		t.Errorf("tidy-html5 must report its version")
+	}
+	if c.Library != TidyHtml5 && c.Html5Tags {
+		t.Errorf("Only tidy-html5 knows HTML5 elements")
+	}
+}
+
+func Test_EnumStrings(t *testing.T) {
+	for e := Raw; e <= Shiftjis; e++ {
+		if p, err := ParseEncoding(e.String()); err != nil || p != e {
+			t.Errorf("Encoding %s does not round-trip", e)
+		}
+	}
+	for _, a := range []AutoBool{False, True, Auto} {
+		if p, err := ParseAutoBool(a.String()); err != nil || p != a {
+			t.Errorf("AutoBool %s does not round-trip", a)
+		}
+	}
+	if n, _ := ParseNewlineMode("crlf"); n != CRLF {
+		t.Errorf("NewlineMode names must be case-insensitive")
+	}
+	if l, _ := ParseAccessLevel("2"); l != Priority2Checks {
+		t.Errorf("AccessLevel must accept libtidy's numeric levels")
+	}
+	if _, err := ParseEncoding("ebcdic"); err == nil {
+		t.Errorf("An unknown encoding must be reported")
+	}
+	if Encoding(42).String() != "Encoding(42)" {
+		t.Errorf("Out of range values must print their number")
+	}
+}
+
+func Test_AutoDetectEncoding(t *testing.T) {
+	tdy, _ := New()
+	defer tdy.Free()
+
+	var output string
+
+	tdy.OutputEncoding(Utf8)
+	tdy.AutoDetectEncoding(true)
+	output, _ = tdy.Tidy("<meta charset=\"windows-1252\"><title>Caf\xe9</title>")
+	if !strings.Contains(output, "Café") {
+		t.Errorf("The charset of the <meta> tag was not used to read the input")
+	}
+
+	tdy.ContentType("text/html; charset=utf-8")
+	output, _ = tdy.Tidy("<meta charset=\"windows-1252\"><title>Café</title>")
+	if !strings.Contains(output, "Café") {
+		t.Errorf("The HTTP Content-Type must take precedence over the <meta> tag")
+	}
+}
+
+func Test_AutoDetectEncodingPerDocument(t *testing.T) {
+	tdy, _ := New()
+	defer tdy.Free()
+
+	tdy.InputEncoding(Utf8)
+	tdy.OutputEncoding(Utf8)
+	tdy.AutoDetectEncoding(true)
+
+	output, _ := tdy.Tidy("<meta charset=\"windows-1252\"><title>Caf\xe9</title>")
+	if !strings.Contains(output, "Café") {
+		t.Errorf("The charset of the <meta> tag was not used to read the input")
+	}
+
+	output, _ = tdy.Tidy("<title>Café</title>")
+	if !strings.Contains(output, "Café") {
+		t.Errorf("The charset detected in one document was used to read the next: %q", output)
+	}
+}
+
+func Test_TidyCharset(t *testing.T) {
+	tdy, _ := New()
+	defer tdy.Free()
+
+	tdy.OutputEncoding(Latin1)
+
+	output, report, _ := tdy.TidyCharset([]byte("<title>\xcf\xf0\xe8\xe2\xe5\xf2</title>"), "windows-1251", "")
+	if !strings.Contains(string(output), "Привет") {
+		t.Errorf("windows-1251 input was not converted to UTF-8")
+	}
+	if len(report.Input) != 0 || len(report.Output) != 0 {
+		t.Errorf("Nothing should have been reported unmapped: %v", report)
+	}
+
+	output, _, _ = tdy.TidyCharset([]byte("<meta charset=\"windows-1251\"><title>\xcf\xf0\xe8\xe2\xe5\xf2</title>"), "windows-1251", "")
+	if strings.Contains(string(output), "windows-1251") || !strings.Contains(string(output), "utf-8") {
+		t.Errorf("Output converted to UTF-8 must not declare windows-1251: %q", output)
+	}
+
+	output, _, _ = tdy.TidyCharset([]byte("<title>Привет</title>"), "utf-8", "koi8-r")
+	if !strings.Contains(string(output), "\xf0\xd2\xc9\xd7\xc5\xd4") {
+		t.Errorf("Output was not converted to KOI8-R")
+	}
+
+	output, _, _ = tdy.TidyCharset([]byte("<title>Café</title>"), "utf-8", "latin1")
+	if !strings.Contains(string(output), "Caf\xe9") {
+		t.Errorf("Output libtidy can write must be returned in latin1: %q", output)
+	}
+	output, _, _ = tdy.TidyCharset([]byte("<title>Café</title>"), "utf-8", "utf-16le")
+	if !strings.Contains(string(output), "C\x00a\x00f\x00\xe9\x00") {
+		t.Errorf("Output libtidy can write must be returned in UTF-16LE: %q", output)
+	}
+
+	latin1, _ := tdy.Tidy("<title>Café</title>")
+	if strings.Contains(latin1, "Café") {
+		t.Errorf("The output encoding must be restored after TidyCharset")
+	}
+}
+
+func Test_Result(t *testing.T) {
+	tdy, _ := New()
+	defer tdy.Free()
+
+	tdy.InputEncoding(Utf8)
+
+	for _, enc := range []Encoding{Utf8, Latin1, Win1252, Utf16le, Utf16, Shiftjis} {
+		tdy.OutputEncoding(enc)
+		res, _ := tdy.TidyBytes([]byte(corruptedHtml))
+		if res == nil {
+			t.Fatalf("%s: no result", enc)
+		}
+		if res.Encoding != enc || res.Charset != enc.Charset() {
+			t.Errorf("%s: result claims to be %s (%s)", enc, res.Encoding, res.Charset)
+		}
+		if !utf8.ValidString(res.String()) || !strings.Contains(res.String(), "<title") {
+			t.Errorf("%s: output was not decoded to UTF-8: %q", enc, res.String())
+		}
+		if strings.HasPrefix(res.String(), "\uFEFF") {
+			t.Errorf("%s: the byte order mark must be dropped", enc)
+		}
+	}
+
+	tdy.OutputEncoding(Utf16le)
+	res, _ := tdy.TidyBytes([]byte(corruptedHtml))
+	if !strings.Contains(string(res.Raw), "<\x00t\x00i\x00t\x00l\x00e\x00") {
+		t.Errorf("Raw output must be left in the output encoding")
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	tdy, _ := New()
+	defer tdy.Free()
+
+	if w := tdy.Validate(); w != nil {
+		t.Errorf("The default options must validate, got %v", w)
+	}
+
+	tdy.OutputXml(true)
+	tdy.ShowBodyOnly(True)
+	tdy.InputEncoding(Shiftjis)
+	tdy.OutputEncoding(Utf8)
+
+	want := map[string]bool{"show-body-only": false, "output-encoding": false}
+	for _, w := range tdy.Validate() {
+		for _, opt := range w.Options {
+			if _, ok := want[opt]; ok {
+				want[opt] = true
+			}
+		}
+	}
+	for opt, found := range want {
+		if !found {
+			t.Errorf("No warning about %s", opt)
+		}
+	}
+
+	tdy.Reset()
+	tdy.ContentType("text/html; charset=utf-8")
+	if w := tdy.Validate(); len(w) != 1 || w[0].Options[0] != "ContentType" {
+		t.Errorf("Options GoTidy keeps must go by their setter's name, got %v", w)
+	}
+	tdy.ContentType("")
+
+	for _, name := range Presets() {
+		tdy.Reset()
+		tdy.ApplyPreset(name)
+		w := tdy.Validate()
+		if name == "html-fragment" {
+			// Kept as gotidy has always had it.
+			if len(w) != 1 || w[0].Options[0] != "show-body-only" {
+				t.Errorf("html-fragment must only be warned about show-body-only, got %v", w)
+			}
+		} else if w != nil {
+			t.Errorf("Preset %s does not validate: %v", name, w)
+		}
+	}
+}
+
+func Test_Overrides(t *testing.T) {
+	tdy, _ := New(WithTidyMark(false))
+	defer tdy.Free()
+
+	var output string
+
+	output, _ = tdy.Tidy(corruptedHtml, WithShowBodyOnly(True), WithWrap(0))
+	if strings.Contains(output, "<html>") {
+		t.Errorf("Overrides were not applied")
+	}
+
+	output, _ = tdy.Tidy(corruptedHtml)
+	if !strings.Contains(output, "<html>") {
+		t.Errorf("Overrides must not outlive the call")
+	}
+
+	if _, err := tdy.Tidy(corruptedHtml, WithTidyMark(true), WithIndentSpaces(-1)); err == nil {
+		t.Errorf("An invalid override must be reported")
+	}
+	output, _ = tdy.Tidy(corruptedHtml)
+	if strings.Contains(output, "HTML Tidy for") {
+		t.Errorf("Options must be restored when an override fails")
+	}
+
+	tdy.Tidy("<p>Unclosed <table><tr><td>error")
+	if opt, _ := optionId("force-output"); tdy.optGetInt(opt) != 0 {
+		t.Errorf("Forcing output after errors must not change the options")
+	}
+}
+
+func Test_ParseSave(t *testing.T) {
+	tdy, _ := New(WithTidyMark(false))
+	defer tdy.Free()
+
+	if _, err := tdy.Save(); err == nil {
+		t.Errorf("Saving before parsing must fail")
+	}
+
+	tdy.Parse([]byte(corruptedHtml))
+
+	html, _ := tdy.SaveHTML()
+	if !strings.HasPrefix(html, "<!DOCTYPE") || strings.HasPrefix(html, "<?xml") {
+		t.Errorf("Not saved as HTML: %q", html)
+	}
+
+	xhtml, _ := tdy.SaveXHTML()
+	if !strings.Contains(xhtml, `xmlns="http://www.w3.org/1999/xhtml"`) {
+		t.Errorf("Not repaired for XHTML: %q", xhtml)
+	}
+
+	xml, _ := tdy.SaveXML(WithAddXmlDecl(true))
+	if !strings.HasPrefix(xml, "<?xml") || strings.Contains(xml, "xmlns=") {
+		t.Errorf("Not saved as XML: %q", xml)
+	}
+	if again, _ := tdy.SaveXHTML(); again != xhtml {
+		t.Errorf("Saving as XHTML must not depend on what was saved before")
+	}
+	tdy.Parse([]byte("<p>Bar!"))
+	if next, _ := tdy.SaveXHTML(); !strings.Contains(next, "Bar!") {
+		t.Errorf("The XHTML copy of a document must not outlive it: %q", next)
+	}
+	tdy.Parse([]byte(corruptedHtml))
+
+	body, _ := tdy.SaveBodyOnly()
+	if strings.Contains(body, "<title") || !strings.Contains(body, "Foo!") {
+		t.Errorf("Not saved as a body fragment: %q", body)
+	}
+
+	again, _ := tdy.SaveHTML()
+	if again != html || strings.Contains(again, "xmlns=") {
+		t.Errorf("Saving must not change the options or the document")
+	}
+}
+
+func Test_TidyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotidy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "page.html")
+	ioutil.WriteFile(path, []byte(corruptedHtml), 0644)
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	os.Chtimes(path, old, old)
+
+	tdy, _ := New()
+	defer tdy.Free()
+
+	if err = tdy.TidyFile(path, ""); err == nil {
+		t.Errorf("Tidying a file without anywhere to write to must fail")
+	}
+
+	tdy.WriteBack(true)
+	tdy.KeepTime(true)
+	tdy.BackupSuffix(".orig")
+	tdy.ErrorFile(filepath.Join(dir, "errors.txt"))
+	tdy.TidyFile(path, "")
+
+	output, _ := ioutil.ReadFile(path)
+	if !strings.Contains(string(output), "<html>") {
+		t.Errorf("The file was not tidied in place: %q", output)
+	}
+	if original, _ := ioutil.ReadFile(path + ".orig"); string(original) != corruptedHtml {
+		t.Errorf("No backup of the original was kept")
+	}
+	if info, _ := os.Stat(path); !info.ModTime().Equal(old) {
+		t.Errorf("Modification time was not kept: %s", info.ModTime())
+	}
+	if errors, _ := ioutil.ReadFile(filepath.Join(dir, "errors.txt")); len(errors) == 0 {
+		t.Errorf("Diagnostics were not written to the error file")
+	}
+
+	other := filepath.Join(dir, "other.html")
+	tdy.TidyFile(path+".orig", other)
+	if _, err = os.Stat(other); err != nil {
+		t.Errorf("Output was not written to the given path: %s", err)
+	}
+
+	declared := filepath.Join(dir, "declared.html")
+	ioutil.WriteFile(declared, []byte("<meta charset=\"windows-1252\"><title>Caf\xe9</title>"), 0644)
+	tdy.OutputEncoding(Utf8)
+	tdy.AutoDetectEncoding(true)
+	tdy.TidyFile(declared, "")
+	if output, _ = ioutil.ReadFile(declared); !strings.Contains(string(output), "Café") {
+		t.Errorf("TidyFile must read a file in the charset it declares: %q", output)
+	}
+}
+
+func Test_Diagnostics(t *testing.T) {
+	tdy, _ := New()
+	defer tdy.Free()
+
+	tdy.Tidy("<p><b>Unclosed</p>")
+	found := false
+	for _, d := range tdy.Diagnostics() {
+		if d.Severity == Warning && d.Line == 1 {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("The unclosed <b> must be reported, got %v", tdy.Diagnostics())
+	}
+
+	if !LibraryCapabilities().MessageCodes {
+		if _, err := tdy.Mute(MissingEndtagBefore); err != ErrUnsupportedOption {
+			t.Errorf("Mute must be refused without message codes")
+		}
+		t.Skip("Linked libtidy has no message codes")
+	}
+
+	tdy.Only(MissingEndtagBefore, MissingDoctype)
+	tdy.Tidy("<p><b>Unclosed</p>")
+	for _, d := range tdy.Diagnostics() {
+		if d.Code != MissingEndtagBefore && d.Code != MissingDoctype {
+			t.Errorf("Only must keep out %s", d.Code)
+		}
+	}
+	tdy.Mute(MissingDoctype)
+	if _, err := tdy.Tidy("<title>T</title><p>Fine</p>"); err != nil {
+		t.Errorf("A muted message must not be returned as an error: %v", err)
+	}
+	if len(tdy.Diagnostics()) != 0 {
+		t.Errorf("Muted messages must not be reported, got %v", tdy.Diagnostics())
+	}
+}
+
+func Test_Language(t *testing.T) {
+	tdy, _ := New()
+	defer tdy.Free()
+
+	langs, err := Languages()
+	if err == ErrUnsupportedOption {
+		if _, err := tdy.Language("fr"); err != ErrUnsupportedOption {
+			t.Errorf("Language must be refused without translations")
+		}
+		t.Skip("Linked libtidy has no translations")
+	}
+	found := false
+	for _, l := range langs {
+		found = found || l == "fr"
+	}
+	if !found {
+		t.Skipf("No French translation among %v", langs)
+	}
+	if _, err := tdy.Language("xx_nowhere"); err == nil {
+		t.Errorf("An unknown language must be rejected")
+	}
+
+	src := "<p><b>Unclosed</p>"
+	tdy.Tidy(src)
+	english := tdy.Diagnostics()
+	tdy.Language("fr")
+	tdy.Tidy(src)
+	french := tdy.Diagnostics()
+	if len(french) != len(english) || len(french) == 0 {
+		t.Fatalf("Got %d diagnostics in French, %d in English", len(french), len(english))
+	}
+	for i := range french {
+		if french[i].Code != english[i].Code {
+			t.Errorf("Codes must not be translated: %s became %s", english[i].Code, french[i].Code)
+		}
+	}
+	if french[0].Message == english[0].Message {
+		t.Errorf("Message %q was not translated", french[0].Message)
+	}
+}
+
+func Test_Audit(t *testing.T) {
+	tdy, _ := New()
+	defer tdy.Free()
+
+	r, err := tdy.Audit([]byte(`<html><head><title>T</title></head><body><img src="x.png"></body></html>`), Priority1Checks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var alt []AccessFinding
+	for _, f := range r.Findings {
+		if f.Checkpoint == "1.1" {
+			alt = append(alt, f)
+		}
+	}
+	if len(alt) == 0 || alt[0].Line != 1 || alt[0].Priority != Priority1Checks {
+		t.Errorf("The missing alt text must be reported at priority 1, got %+v", r.Findings)
+	}
+	if r.Passed(Priority1Checks) {
+		t.Errorf("Priority 1 must fail")
+	}
+	if opt, _ := optionId("accessibility-check"); tdy.optGetInt(opt) != 0 {
+		t.Errorf("Audit must leave the check level as it was")
+	}
+}
+
+func Test_Lint(t *testing.T) {
+	tdy, _ := New()
+	defer tdy.Free()
+
+	r, err := tdy.Lint([]byte("<p><foo>Bad</foo>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Failed(Error) || r.ExitCode(Error) != 2 {
+		t.Errorf("An unknown element must fail the lint: %s", r.Summary())
+	}
+	if opt, _ := optionId("force-output"); tdy.optGetInt(opt) != 0 {
+		t.Errorf("Lint must not force output")
+	}
+}
+
+func Test_Fixes(t *testing.T) {
+	tdy, _ := New()
+	defer tdy.Free()
+
+	tdy.Tidy("<p><b>Bold</p></div>")
+	fixes, _ := tdy.Fixes()
+	kinds := map[FixKind]string{}
+	for _, f := range fixes {
+		kinds[f.Kind] = f.Element
+	}
+	if kinds[Inserted] != "b" || kinds[Discarded] != "div" {
+		t.Errorf("The closed <b> and dropped </div> must be reported, got %v", fixes)
+	}
+}
+
+func Test_Diff(t *testing.T) {
+	tdy, _ := New(WithShowBodyOnly(True), WithIndent(False), WithTidyMark(false))
+	defer tdy.Free()
+
+	diff, _ := tdy.Diff([]byte("<p>One\n<p>Two\n"))
+	if !strings.HasPrefix(diff, "--- input\n+++ tidied\n@@ ") || !strings.Contains(diff, "+<p>One</p>") {
+		t.Errorf("Diff must show the end tags added, got\n%s", diff)
+	}
+
+	tdy.StructuralDiff(true)
+	if diff, _ := tdy.Diff([]byte("<p>One</p>\n\n\n<p>Two</p>")); diff != "" {
+		t.Errorf("A structural diff must ignore reformatting, got\n%s", diff)
+	}
+	if diff, _ := tdy.Diff([]byte("<p>One<p>Two")); !strings.Contains(diff, "+</p>") {
+		t.Errorf("A structural diff must show the end tags added, got\n%s", diff)
+	}
+}
+
+func Test_SourceMap(t *testing.T) {
+	tdy, _ := New(WithShowBodyOnly(True), WithIndent(True))
+	defer tdy.Free()
+
+	tdy.Tidy("<p>One\n\n\n<p>Two <em>three</em>")
+	if tdy.SourceMap() != nil {
+		t.Errorf("No map must be made unless asked for")
+	}
+
+	tdy.MapPositions(true)
+	output, _ := tdy.Tidy("<p>One\n\n\n<p>Two <em>three</em>")
+	m := tdy.SourceMap()
+	if m == nil {
+		t.Fatal("MapPositions must make a map")
+	}
+	if line, _, ok := m.Offset(strings.Index(output, "<em>")); !ok || line != 4 {
+		t.Errorf("<em> maps to line %d; want 4", line)
+	}
+	if line, column, _ := m.Position(1, 1); line != 1 || column != 1 {
+		t.Errorf("The first <p> maps to %d:%d; want 1:1", line, column)
+	}
+}
+
+func Test_Stabilize(t *testing.T) {
+	tdy, _ := New(WithIndent(Auto))
+	defer tdy.Free()
+
+	if ok, err := tdy.Stabilize(-1); ok || err == nil {
+		t.Errorf("A negative number of passes must be rejected")
+	}
+	tdy.Stabilize(3)
+	output, err := tdy.Tidy(corruptedHtml)
+	if _, unstable := err.(*UnstableError); unstable {
+		t.Fatal(err)
+	}
+	tdy.Stabilize(0)
+	if again, _ := tdy.Tidy(output); again != output {
+		t.Errorf("Stabilized output changed when tidied again:\n%s", UnifiedDiff("once", output, "twice", again))
+	}
+
+	tdy.Stabilize(3)
+	tdy.Tidy(corruptedHtml)
+	if _, err = tdy.Save(); err == nil {
+		t.Errorf("The document the passes leave behind must not be kept for Save")
+	}
+}
+
+func Test_HtmlFragmentPreset(t *testing.T) {
+	opts, _ := Preset("html-fragment")
+	tdy, err := New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tdy.Free()
+
+	// XML output, as the gotidy binary has always written it.
+	output, _ := tdy.Tidy("<p>One<br>Two & three")
+	if strings.HasPrefix(output, "<?xml") || !strings.Contains(output, "<br />") || !strings.Contains(output, "&amp;") {
+		t.Errorf("html-fragment must write well-formed XML without a declaration, got %q", output)
+	}
+}
+
+// Every preset, stabilized, must give output that tidying again leaves alone.
+func Test_PresetsIdempotent(t *testing.T) {
+	files, _ := filepath.Glob(filepath.Join("testdata", "idempotent", "*.html"))
+	if len(files) == 0 {
+		t.Fatal("The idempotency corpus is missing")
+	}
+	for _, name := range Presets() {
+		opts, _ := Preset(name)
+		tdy, err := New(opts...)
+		if err != nil {
+			t.Errorf("Preset %s: %s", name, err)
+			continue
+		}
+		for _, file := range files {
+			src, _ := ioutil.ReadFile(file)
+			tdy.Stabilize(5)
+			res, err := tdy.TidyBytes(src)
+			if _, unstable := err.(*UnstableError); unstable || res == nil {
+				t.Errorf("Preset %s, %s: %v", name, file, err)
+				continue
+			}
+			tdy.Stabilize(0)
+			again, _ := tdy.TidyBytes(res.Raw, WithInputEncoding(res.Encoding))
+			if again == nil || again.String() != res.String() {
+				t.Errorf("Preset %s, %s: output is not stable", name, file)
+			}
+		}
+		tdy.Free()
+	}
+}
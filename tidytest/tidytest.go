@@ -0,0 +1,142 @@
+//go:build cgo
+// +build cgo
+
+// Package tidytest compares HTML in tests by what it means rather than how it
+// is written. Both sides are put through Tidy with a fixed, canonical
+// configuration first, so that attribute order, whitespace, line breaks and
+// the way characters are escaped don't cause failures.
+package tidytest
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	tidy "github.com/rniedosmialek/GoTidy"
+)
+
+// update is named after the package so as not to clash with an -update flag
+// of the package under test.
+var update = flag.Bool("tidytest.update", false, "rewrite golden files with the output got")
+
+// canonical is the configuration HTML is compared in: one element to a line,
+// no wrapping, attributes sorted and characters written out in UTF-8 rather
+// than as entities.
+var canonical = []tidy.Option{
+	tidy.WithShowBodyOnly(tidy.Auto),
+	tidy.WithIndent(tidy.True),
+	tidy.WithIndentSpaces(2),
+	tidy.WithWrap(0),
+	tidy.WithTidyMark(false),
+	tidy.WithForceOutput(true),
+	tidy.WithInputEncoding(tidy.Utf8),
+	tidy.WithOutputEncoding(tidy.Utf8),
+	tidy.WithNumericEntities(false),
+	tidy.With("quote-nbsp", false),
+	tidy.With("quote-marks", false),
+	tidy.With("newline", tidy.LF),
+}
+
+// Canonical returns html in canonical form. Documents come out as documents
+// and fragments as fragments. Where the linked libtidy can't sort attributes
+// (see tidy.Capabilities), they are sorted here instead.
+func Canonical(html string) (string, error) {
+	t, err := tidy.New(canonical...)
+	if err != nil {
+		return "", err
+	}
+	defer t.Free()
+	_, err = t.SortAttributes(tidy.Alpha)
+	sortHere := err == tidy.ErrUnsupportedOption
+	if err != nil && !sortHere {
+		return "", err
+	}
+	t.Stabilize(3)
+
+	res, err := t.TidyBytes([]byte(html))
+	if _, unstable := err.(*tidy.UnstableError); unstable || res == nil {
+		return "", err
+	}
+	// Complaints about the markup don't stop a comparison.
+	if sortHere {
+		return sortAttributes(res.String()), nil
+	}
+	return res.String(), nil
+}
+
+var (
+	startTag  = regexp.MustCompile(`<[a-zA-Z][^\s/>]*((?:\s+[^\s"'=/>]+(?:="[^"]*")?)+)\s*/?>`)
+	attribute = regexp.MustCompile(`[^\s"'=/>]+(?:="[^"]*")?`)
+)
+
+// sortAttributes puts the attributes of the start tags in html, written the
+// way libtidy writes them, in alphabetical order.
+func sortAttributes(html string) string {
+	return startTag.ReplaceAllStringFunc(html, func(tag string) string {
+		m := startTag.FindStringSubmatchIndex(tag)
+		attrs := attribute.FindAllString(tag[m[2]:m[3]], -1)
+		sort.Strings(attrs)
+		return tag[:m[2]] + " " + strings.Join(attrs, " ") + tag[m[3]:]
+	})
+}
+
+// AssertEquivalentHTML fails the test, showing a diff of the canonical forms,
+// unless want and got are the same HTML. It reports whether they were.
+func AssertEquivalentHTML(t testing.TB, want, got string) bool {
+	t.Helper()
+	w, err := Canonical(want)
+	if err != nil {
+		t.Errorf("tidytest: want: %v", err)
+		return false
+	}
+	g, err := Canonical(got)
+	if err != nil {
+		t.Errorf("tidytest: got: %v", err)
+		return false
+	}
+	if diff := tidy.UnifiedDiff("want", w, "got", g); diff != "" {
+		t.Errorf("HTML differs:\n%s", diff)
+		return false
+	}
+	return true
+}
+
+// AssertGolden compares got with the HTML in the golden file at path, like
+// AssertEquivalentHTML. Run the test with -tidytest.update to write got to
+// the file, in canonical form, instead.
+func AssertGolden(t testing.TB, path string, got string) bool {
+	t.Helper()
+	if *update {
+		g, err := Canonical(got)
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(path), 0777)
+		}
+		if err == nil {
+			err = ioutil.WriteFile(path, []byte(g), 0666)
+		}
+		if err != nil {
+			t.Errorf("tidytest: %v", err)
+			return false
+		}
+		return true
+	}
+
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Errorf("tidytest: %v (run with -tidytest.update to create it)", err)
+		return false
+	}
+	return AssertEquivalentHTML(t, string(want), got)
+}
+
+// Golden is AssertGolden with the file named after the test, under
+// testdata/golden.
+func Golden(t testing.TB, got string) bool {
+	t.Helper()
+	return AssertGolden(t, filepath.Join("testdata", "golden", filepath.FromSlash(t.Name())+".html"), got)
+}
@@ -0,0 +1,83 @@
+//go:build cgo
+// +build cgo
+
+package tidytest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Canonical(t *testing.T) {
+	a, err := Canonical(`<p id="x"   class='y'>One &amp; two&nbsp;three</p>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := Canonical("<p class=y id=x>\n  One &#38; two three\n</p>\n")
+	if a != b {
+		t.Errorf("Equivalent HTML must have one canonical form:\n%s\n%s", a, b)
+	}
+	if strings.Contains(a, "<html") {
+		t.Errorf("A fragment must stay a fragment:\n%s", a)
+	}
+}
+
+func Test_SortAttributes(t *testing.T) {
+	got := sortAttributes(`<p id="x" class="y"><input type="checkbox" checked name="a-b" /> a<b</p>`)
+	want := `<p class="y" id="x"><input checked name="a-b" type="checkbox" /> a<b</p>`
+	if got != want {
+		t.Errorf("Attributes were not sorted:\n%s\n%s", got, want)
+	}
+}
+
+// recorder stands in for a test, to check what the helpers report.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, format)
+}
+
+func Test_AssertEquivalentHTML(t *testing.T) {
+	r := &recorder{TB: t}
+	if !AssertEquivalentHTML(r, "<ul><li>A<li>B</ul>", "<ul>\n<li>A</li>\n<li>B</li>\n</ul>") {
+		t.Errorf("Reformatted HTML must be equivalent: %v", r.failures)
+	}
+	if AssertEquivalentHTML(r, "<p>A</p>", "<p>B</p>") || len(r.failures) != 1 {
+		t.Errorf("Different text must be reported once")
+	}
+}
+
+func Test_AssertGolden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tidytest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "page.html")
+
+	defer func(saved bool) { *update = saved }(*update)
+	*update = false
+
+	r := &recorder{TB: t}
+	if AssertGolden(r, path, "<p>A</p>") {
+		t.Errorf("A missing golden file must fail")
+	}
+
+	*update = true
+	if !AssertGolden(r, path, "<p>A   &amp; B</p>") {
+		t.Fatalf("-tidytest.update must write the golden file: %v", r.failures)
+	}
+
+	*update = false
+	if !AssertGolden(r, path, "<p>\nA &#38; B\n</p>") {
+		t.Errorf("Golden file must match equivalent HTML: %v", r.failures)
+	}
+}
@@ -0,0 +1,435 @@
+package tidy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// A Charset converts text between some character encoding and UTF-8, so that
+// documents in encodings libtidy cannot read can still be tidied. GoTidy
+// knows the common single-byte charsets, GB18030 and EUC-KR; others can be
+// added with RegisterCharset, for instance by wrapping the encoders of
+// golang.org/x/text.
+type Charset interface {
+	// Decode converts src to UTF-8. Bytes that don't map to a character are
+	// replaced by U+FFFD and reported.
+	Decode(src []byte) (string, []Unmapped)
+
+	// Encode converts s from UTF-8. Characters the charset lacks are written
+	// as HTML numeric character references and reported.
+	Encode(s string) ([]byte, []Unmapped)
+}
+
+// Unmapped is a character that could not be converted. Offset is its byte
+// position in the text being converted. When decoding, Rune is
+// utf8.RuneError; when encoding, it is the character the charset lacks.
+type Unmapped struct {
+	Offset int
+	Rune   rune
+}
+
+// TranscodeReport lists what could not be converted on the way into and out
+// of libtidy.
+type TranscodeReport struct {
+	Input  []Unmapped // Offsets into the original input.
+	Output []Unmapped // Offsets into the tidied UTF-8 output.
+}
+
+var (
+	charsetsMu sync.RWMutex
+	charsets   = map[string]Charset{}
+)
+
+func init() {
+	builtin := []struct {
+		table  *[128]rune
+		labels []string
+	}{
+		{&windows1250, []string{"windows-1250", "cp1250", "x-cp1250"}},
+		{&windows1251, []string{"windows-1251", "cp1251", "x-cp1251"}},
+		{&windows1252, []string{"windows-1252", "cp1252", "x-cp1252"}},
+		{&windows1253, []string{"windows-1253", "cp1253", "x-cp1253"}},
+		{&windows1254, []string{"windows-1254", "cp1254", "x-cp1254", "iso-8859-9", "latin5", "l5"}},
+		{&iso8859_2, []string{"iso-8859-2", "iso8859-2", "iso_8859-2", "latin2", "l2", "csisolatin2"}},
+		{&iso8859_5, []string{"iso-8859-5", "iso8859-5", "iso_8859-5", "cyrillic", "csisolatincyrillic"}},
+		{&iso8859_7, []string{"iso-8859-7", "iso8859-7", "iso_8859-7", "greek", "greek8", "csisolatingreek"}},
+		{&iso8859_15, []string{"iso-8859-15", "iso8859-15", "iso_8859-15", "latin9", "l9"}},
+		{&koi8r, []string{"koi8-r", "koi8r", "koi8", "koi", "cskoi8r"}},
+		{&koi8u, []string{"koi8-u", "koi8-ru"}},
+		{&ibm858, []string{"ibm858", "cp858", "ibm00858"}},
+		{&ibm866, []string{"ibm866", "cp866", "866", "csibm866"}},
+		{&macintosh, []string{"macintosh", "mac", "x-mac-roman", "csmacintosh"}},
+	}
+	for _, b := range builtin {
+		RegisterCharset(&singleByte{table: b.table}, b.labels...)
+	}
+	RegisterCharset(latin1{}, "iso-8859-1", "iso8859-1", "iso_8859-1", "latin1", "l1")
+	RegisterCharset(utf8Charset{}, "utf-8", "utf8")
+	RegisterCharset(utf16Charset{bigEndian: false}, "utf-16le")
+	RegisterCharset(utf16Charset{bigEndian: true}, "utf-16be")
+	RegisterCharset(utf16Charset{bigEndian: true, bom: true}, "utf-16")
+	RegisterCharset(&gb18030{}, "gb18030", "gbk", "gb2312", "cp936", "windows-936", "x-gbk", "csgb2312", "chinese")
+	RegisterCharset(&euckr{}, "euc-kr", "cseuckr", "cp949", "windows-949", "ks_c_5601-1987", "ksc5601", "korean", "csksc56011987")
+}
+
+// RegisterCharset makes c available under each of the given labels, replacing
+// any charset previously registered under them.
+func RegisterCharset(c Charset, labels ...string) {
+	charsetsMu.Lock()
+	defer charsetsMu.Unlock()
+	for _, label := range labels {
+		charsets[normalizeLabel(label)] = c
+	}
+}
+
+// LookupCharset returns the Charset registered under label.
+func LookupCharset(label string) (Charset, error) {
+	charsetsMu.RLock()
+	defer charsetsMu.RUnlock()
+	if c, ok := charsets[normalizeLabel(label)]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("Charset %q is not registered", label)
+}
+
+// singleByte is a charset whose lower half is ASCII and whose upper half is
+// given by a table.
+type singleByte struct {
+	table   *[128]rune
+	once    sync.Once
+	reverse map[rune]byte
+}
+
+func (c *singleByte) Decode(src []byte) (string, []Unmapped) {
+	var unmapped []Unmapped
+	buf := make([]byte, 0, len(src))
+	for i, b := range src {
+		r := rune(b)
+		if b >= 0x80 {
+			r = c.table[b-0x80]
+			if r == utf8.RuneError {
+				unmapped = append(unmapped, Unmapped{i, utf8.RuneError})
+			}
+		}
+		buf = appendRune(buf, r)
+	}
+	return string(buf), unmapped
+}
+
+func (c *singleByte) Encode(s string) ([]byte, []Unmapped) {
+	c.once.Do(func() {
+		c.reverse = make(map[rune]byte, 128)
+		for i, r := range c.table {
+			if r != utf8.RuneError {
+				c.reverse[r] = byte(i + 0x80)
+			}
+		}
+	})
+	var unmapped []Unmapped
+	buf := make([]byte, 0, len(s))
+	for i, r := range s {
+		if r < 0x80 {
+			buf = append(buf, byte(r))
+		} else if b, ok := c.reverse[r]; ok {
+			buf = append(buf, b)
+		} else {
+			unmapped = append(unmapped, Unmapped{i, r})
+			buf = appendCharRef(buf, r)
+		}
+	}
+	return buf, unmapped
+}
+
+// latin1 is ISO-8859-1, whose code points are those of Unicode.
+type latin1 struct{}
+
+func (latin1) Decode(src []byte) (string, []Unmapped) {
+	buf := make([]byte, 0, len(src))
+	for _, b := range src {
+		buf = appendRune(buf, rune(b))
+	}
+	return string(buf), nil
+}
+
+func (latin1) Encode(s string) ([]byte, []Unmapped) {
+	var unmapped []Unmapped
+	buf := make([]byte, 0, len(s))
+	for i, r := range s {
+		if r <= 0xFF {
+			buf = append(buf, byte(r))
+		} else {
+			unmapped = append(unmapped, Unmapped{i, r})
+			buf = appendCharRef(buf, r)
+		}
+	}
+	return buf, unmapped
+}
+
+// utf8Charset passes UTF-8 through, reporting invalid sequences.
+type utf8Charset struct{}
+
+func (utf8Charset) Decode(src []byte) (string, []Unmapped) {
+	if utf8.Valid(src) {
+		return string(src), nil
+	}
+	var unmapped []Unmapped
+	buf := make([]byte, 0, len(src))
+	for i := 0; i < len(src); {
+		r, size := utf8.DecodeRune(src[i:])
+		if r == utf8.RuneError && size <= 1 {
+			unmapped = append(unmapped, Unmapped{i, utf8.RuneError})
+		}
+		buf = appendRune(buf, r)
+		i += size
+	}
+	return string(buf), unmapped
+}
+
+func (utf8Charset) Encode(s string) ([]byte, []Unmapped) {
+	return []byte(s), nil
+}
+
+// utf16Charset is UTF-16 in either byte order. When decoding, a byte order
+// mark overrides the byte order and is dropped; when encoding, one is written
+// if bom is set.
+type utf16Charset struct {
+	bigEndian bool
+	bom       bool
+}
+
+func (c utf16Charset) Decode(src []byte) (string, []Unmapped) {
+	bigEndian := c.bigEndian
+	start := 0
+	if len(src) >= 2 {
+		switch {
+		case src[0] == 0xFE && src[1] == 0xFF:
+			bigEndian, start = true, 2
+		case src[0] == 0xFF && src[1] == 0xFE:
+			bigEndian, start = false, 2
+		}
+	}
+
+	var unmapped []Unmapped
+	units := make([]uint16, 0, len(src)/2)
+	offsets := make([]int, 0, len(src)/2)
+	for i := start; i+1 < len(src); i += 2 {
+		if bigEndian {
+			units = append(units, uint16(src[i])<<8|uint16(src[i+1]))
+		} else {
+			units = append(units, uint16(src[i+1])<<8|uint16(src[i]))
+		}
+		offsets = append(offsets, i)
+	}
+
+	buf := make([]byte, 0, len(src))
+	for i := 0; i < len(units); i++ {
+		r := rune(units[i])
+		switch {
+		case utf16.IsSurrogate(r) && i+1 < len(units):
+			if dec := utf16.DecodeRune(r, rune(units[i+1])); dec != utf8.RuneError {
+				r = dec
+				i++
+				break
+			}
+			fallthrough
+		case utf16.IsSurrogate(r):
+			unmapped = append(unmapped, Unmapped{offsets[i], utf8.RuneError})
+			r = utf8.RuneError
+		}
+		buf = appendRune(buf, r)
+	}
+	if (len(src)-start)%2 != 0 {
+		unmapped = append(unmapped, Unmapped{len(src) - 1, utf8.RuneError})
+		buf = appendRune(buf, utf8.RuneError)
+	}
+	return string(buf), unmapped
+}
+
+func (c utf16Charset) Encode(s string) ([]byte, []Unmapped) {
+	units := utf16.Encode([]rune(s))
+	if c.bom {
+		units = append([]uint16{0xFEFF}, units...)
+	}
+	buf := make([]byte, 0, 2*len(units))
+	for _, u := range units {
+		if c.bigEndian {
+			buf = append(buf, byte(u>>8), byte(u))
+		} else {
+			buf = append(buf, byte(u), byte(u>>8))
+		}
+	}
+	return buf, nil
+}
+
+// doubleByteReverse maps the characters of a double-byte table to their
+// index in it.
+func doubleByteReverse(table []uint16) map[rune]int {
+	reverse := make(map[rune]int, len(table))
+	for i, r := range table {
+		if r != 0 {
+			reverse[rune(r)] = i
+		}
+	}
+	return reverse
+}
+
+// euckr is EUC-KR as browsers read it, that is with the windows-949
+// extensions for the Hangul syllables KS X 1001 lacks.
+type euckr struct {
+	once    sync.Once
+	reverse map[rune]int
+}
+
+func (c *euckr) Decode(src []byte) (string, []Unmapped) {
+	var unmapped []Unmapped
+	buf := make([]byte, 0, len(src))
+	for i := 0; i < len(src); {
+		b := src[i]
+		if b < 0x80 {
+			buf = append(buf, b)
+			i++
+			continue
+		}
+		r, size := utf8.RuneError, 1
+		if b >= 0x81 && b <= 0xFE && i+1 < len(src) {
+			if t := src[i+1]; t >= 0x41 && t <= 0xFE {
+				if v := cp949[int(b-0x81)*190+int(t-0x41)]; v != 0 {
+					r, size = rune(v), 2
+				} else if t >= 0x80 {
+					size = 2 // An ASCII trail byte is read again on its own.
+				}
+			}
+		}
+		if r == utf8.RuneError {
+			unmapped = append(unmapped, Unmapped{i, utf8.RuneError})
+		}
+		buf = appendRune(buf, r)
+		i += size
+	}
+	return string(buf), unmapped
+}
+
+func (c *euckr) Encode(s string) ([]byte, []Unmapped) {
+	c.once.Do(func() { c.reverse = doubleByteReverse(cp949[:]) })
+	var unmapped []Unmapped
+	buf := make([]byte, 0, len(s))
+	for i, r := range s {
+		if r < 0x80 {
+			buf = append(buf, byte(r))
+		} else if n, ok := c.reverse[r]; ok {
+			buf = append(buf, byte(n/190+0x81), byte(n%190+0x41))
+		} else {
+			unmapped = append(unmapped, Unmapped{i, r})
+			buf = appendCharRef(buf, r)
+		}
+	}
+	return buf, unmapped
+}
+
+// gb18030 is GB18030, which covers all of Unicode: what GBK lacks is written
+// as four-byte sequences. It reads GBK and GB2312, which it extends.
+type gb18030 struct {
+	once    sync.Once
+	reverse map[rune]int
+}
+
+// The four-byte sequences are numbered from 0 for 81 30 81 30; those from
+// gb18030Supplementary on stand for U+10000 onwards.
+const gb18030Supplementary = 189000
+
+func (c *gb18030) Decode(src []byte) (string, []Unmapped) {
+	isDigit := func(b byte) bool { return b >= 0x30 && b <= 0x39 }
+	isLead := func(b byte) bool { return b >= 0x81 && b <= 0xFE }
+
+	var unmapped []Unmapped
+	buf := make([]byte, 0, len(src))
+	for i := 0; i < len(src); {
+		b := src[i]
+		if b < 0x80 {
+			buf = append(buf, b)
+			i++
+			continue
+		}
+		r, ok, size := utf8.RuneError, false, 1
+		switch {
+		case !isLead(b):
+		case i+3 < len(src) && isDigit(src[i+1]) && isLead(src[i+2]) && isDigit(src[i+3]):
+			n := ((int(b-0x81)*10+int(src[i+1]-0x30))*126+int(src[i+2]-0x81))*10 + int(src[i+3]-0x30)
+			r, ok = gb18030Rune(n)
+			size = 4
+		case i+1 < len(src) && src[i+1] >= 0x40 && src[i+1] <= 0xFE && src[i+1] != 0x7F:
+			t := int(src[i+1]) - 0x40
+			if t > 0x3F {
+				t-- // No trail byte 0x7F.
+			}
+			r, ok, size = rune(gb18030TwoByte[int(b-0x81)*190+t]), true, 2
+		}
+		if !ok {
+			unmapped = append(unmapped, Unmapped{i, utf8.RuneError})
+		}
+		buf = appendRune(buf, r)
+		i += size
+	}
+	return string(buf), unmapped
+}
+
+func (c *gb18030) Encode(s string) ([]byte, []Unmapped) {
+	c.once.Do(func() { c.reverse = doubleByteReverse(gb18030TwoByte[:]) })
+	buf := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r < 0x80 {
+			buf = append(buf, byte(r))
+			continue
+		}
+		if n, ok := c.reverse[r]; ok {
+			t := n%190 + 0x40
+			if t >= 0x7F {
+				t++
+			}
+			buf = append(buf, byte(n/190+0x81), byte(t))
+			continue
+		}
+		n := gb18030Index(r)
+		buf = append(buf, byte(n/12600+0x81), byte(n/1260%10+0x30), byte(n/10%126+0x81), byte(n%10+0x30))
+	}
+	return buf, nil
+}
+
+// gb18030Rune returns the character the four-byte sequence numbered n stands
+// for, if any.
+func gb18030Rune(n int) (rune, bool) {
+	switch {
+	case n < gb18030BMPEnd:
+		i := sort.Search(len(gb18030Ranges), func(i int) bool { return int(gb18030Ranges[i][0]) > n }) - 1
+		return rune(int(gb18030Ranges[i][1]) + n - int(gb18030Ranges[i][0])), true
+	case n >= gb18030Supplementary && n < gb18030Supplementary+0x100000:
+		return rune(n-gb18030Supplementary) + 0x10000, true
+	}
+	return utf8.RuneError, false
+}
+
+// gb18030Index numbers the four-byte sequence for r, which must not be in
+// the double-byte table.
+func gb18030Index(r rune) int {
+	if r >= 0x10000 {
+		return int(r-0x10000) + gb18030Supplementary
+	}
+	i := sort.Search(len(gb18030Ranges), func(i int) bool { return rune(gb18030Ranges[i][1]) > r }) - 1
+	return int(gb18030Ranges[i][0]) + int(r) - int(gb18030Ranges[i][1])
+}
+
+func appendRune(buf []byte, r rune) []byte {
+	var tmp [utf8.UTFMax]byte
+	n := utf8.EncodeRune(tmp[:], r)
+	return append(buf, tmp[:n]...)
+}
+
+func appendCharRef(buf []byte, r rune) []byte {
+	buf = append(buf, "&#"...)
+	buf = strconv.AppendInt(buf, int64(r), 10)
+	return append(buf, ';')
+}
@@ -0,0 +1,125 @@
+package tidy
+
+import (
+	"testing"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+func Test_CharsetRoundTrip(t *testing.T) {
+	tests := []struct {
+		label   string
+		encoded string
+		text    string
+	}{
+		{"windows-1251", "\xcf\xf0\xe8\xe2\xe5\xf2", "Привет"},
+		{"KOI8-R", "\xf0\xd2\xc9\xd7\xc5\xd4", "Привет"},
+		{"iso-8859-2", "Za\xbf\xf3\xb3\xe6", "Zażółć"},
+		{"latin1", "Caf\xe9", "Café"},
+		{"EUC-KR", "\xc7\xd1\xb1\xb9\xbe\xee \x8c\x63\xb9\xe6\xb0\xa2\xc7\xcf", "한국어 똠방각하"},
+		{"GB18030", "\xd6\xd0\xce\xc4 \xa8\xa6\x81\x30\x84\x38\x94\x39\xfc\x36", "中文 é©😀"},
+		{"gbk", "\xd6\xd0\xce\xc4", "中文"},
+	}
+	for _, test := range tests {
+		c, err := LookupCharset(test.label)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, unmapped := c.Decode([]byte(test.encoded))
+		if text != test.text || len(unmapped) != 0 {
+			t.Errorf("%s: decoded %q to %q, %v", test.label, test.encoded, text, unmapped)
+		}
+		encoded, unmapped := c.Encode(test.text)
+		if string(encoded) != test.encoded || len(unmapped) != 0 {
+			t.Errorf("%s: encoded %q to %q, %v", test.label, test.text, encoded, unmapped)
+		}
+	}
+}
+
+func Test_CharsetUnmapped(t *testing.T) {
+	c, _ := LookupCharset("windows-1251")
+
+	text, unmapped := c.Decode([]byte("a\x98b"))
+	if text != "a�b" || len(unmapped) != 1 || unmapped[0] != (Unmapped{1, utf8.RuneError}) {
+		t.Errorf("Undefined byte decoded to %q, %v", text, unmapped)
+	}
+
+	encoded, unmapped := c.Encode("Ж€ 世")
+	if string(encoded) != "\xc6\x88 &#19990;" || len(unmapped) != 1 || unmapped[0] != (Unmapped{6, '世'}) {
+		t.Errorf("Unmappable character encoded to %q, %v", encoded, unmapped)
+	}
+}
+
+func Test_GB18030AllOfUnicode(t *testing.T) {
+	c, _ := LookupCharset("gb18030")
+	var runes []rune
+	for r := rune(0); r <= unicode.MaxRune; r++ {
+		if !utf16.IsSurrogate(r) {
+			runes = append(runes, r)
+		}
+	}
+	encoded, unmapped := c.Encode(string(runes))
+	if len(unmapped) != 0 {
+		t.Fatalf("GB18030 must encode every character, %d were not", len(unmapped))
+	}
+	text, unmapped := c.Decode(encoded)
+	if text != string(runes) || len(unmapped) != 0 {
+		t.Errorf("GB18030 did not decode what it encoded: %d unmapped", len(unmapped))
+	}
+}
+
+func Test_CJKCharsetUnmapped(t *testing.T) {
+	gb, _ := LookupCharset("gb18030")
+	text, unmapped := gb.Decode([]byte("a\xffb\x84\x31\xa5\x30c\xd6"))
+	if text != "a�b�c�" || len(unmapped) != 3 || unmapped[1].Offset != 3 || unmapped[2].Offset != 8 {
+		t.Errorf("Invalid GB18030 decoded to %q, %v", text, unmapped)
+	}
+
+	kr, _ := LookupCharset("euc-kr")
+	text, unmapped = kr.Decode([]byte("\xc9\x41b"))
+	if text != "�Ab" || len(unmapped) != 1 {
+		t.Errorf("An undefined EUC-KR pair must leave an ASCII trail byte alone, got %q, %v", text, unmapped)
+	}
+	encoded, unmapped := kr.Encode("가中€")
+	if string(encoded) != "\xb0\xa1\xf1\xe9\xa2\xe6" || len(unmapped) != 0 {
+		t.Errorf("EUC-KR encoded to %q, %v", encoded, unmapped)
+	}
+	encoded, unmapped = kr.Encode("가ж😀")
+	if string(encoded) != "\xb0\xa1\xac\xd8&#128512;" || len(unmapped) != 1 || unmapped[0] != (Unmapped{5, '😀'}) {
+		t.Errorf("Unmappable character encoded to %q, %v", encoded, unmapped)
+	}
+}
+
+func Test_RegisterCharset(t *testing.T) {
+	if _, err := LookupCharset("x-test-charset"); err == nil {
+		t.Fatal("An unregistered charset must be reported")
+	}
+	RegisterCharset(latin1{}, "X-Test-Charset")
+	if _, err := LookupCharset("x-test-charset"); err != nil {
+		t.Errorf("Charset labels must be case-insensitive")
+	}
+}
+
+func Test_Utf16Charset(t *testing.T) {
+	le, _ := LookupCharset("utf-16le")
+	text, unmapped := le.Decode([]byte("H\x00i\x00=\xd8\x00\xde"))
+	if text != "Hi😀" || len(unmapped) != 0 {
+		t.Errorf("UTF-16LE decoded to %q, %v", text, unmapped)
+	}
+
+	be, _ := LookupCharset("utf-16")
+	encoded, _ := be.Encode("Hi")
+	if string(encoded) != "\xfe\xff\x00H\x00i" {
+		t.Errorf("UTF-16 encoded to %q", encoded)
+	}
+	text, _ = be.Decode([]byte("\xff\xfeH\x00i\x00"))
+	if text != "Hi" {
+		t.Errorf("A byte order mark must decide the byte order, got %q", text)
+	}
+
+	text, unmapped = le.Decode([]byte("\x00\xd8H\x00"))
+	if text != "�H" || len(unmapped) != 1 {
+		t.Errorf("A lone surrogate decoded to %q, %v", text, unmapped)
+	}
+}
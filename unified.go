@@ -0,0 +1,335 @@
+package tidy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// UnifiedDiff returns the differences between two texts as a unified diff,
+// in the format of diff -u, labelling them fromName and toName. It returns ""
+// if the texts are the same.
+func UnifiedDiff(fromName, from, toName, to string) string {
+	if from == to {
+		return ""
+	}
+	a, b := splitLines(from), splitLines(to)
+	ops := diffLines(a, b)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for start := 0; start < len(ops); {
+		// Find the next change and take in the changes that follow it
+		// closely enough to share context.
+		for start < len(ops) && ops[start].op == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		end := start
+		for i := start; i < len(ops) && i-end <= 2*diffContext; i++ {
+			if ops[i].op != ' ' {
+				end = i + 1
+			}
+		}
+		lo, hi := start-diffContext, end+diffContext
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > len(ops) {
+			hi = len(ops)
+		}
+		writeHunk(&out, ops[lo:hi])
+		start = hi
+	}
+	return out.String()
+}
+
+// diffOp is one line of a diff: ' ' if unchanged, '-' if only in the first
+// text and '+' if only in the second. ai and bi are its line numbers there,
+// counting from 0.
+type diffOp struct {
+	op     byte
+	line   string
+	ai, bi int
+}
+
+func writeHunk(out *strings.Builder, ops []diffOp) {
+	aLen, bLen := 0, 0
+	for _, o := range ops {
+		if o.op != '+' {
+			aLen++
+		}
+		if o.op != '-' {
+			bLen++
+		}
+	}
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(ops[0].ai, aLen), hunkRange(ops[0].bi, bLen))
+	for _, o := range ops {
+		out.WriteByte(o.op)
+		out.WriteString(o.line)
+		if !strings.HasSuffix(o.line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats the start and length of a hunk the way diff does: an
+// empty range starts at the line before it.
+func hunkRange(start, n int) string {
+	switch n {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprint(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
+
+// splitLines splits s after each newline, keeping the newlines so that a
+// missing one at the end shows up as a difference.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines finds a shortest edit script from a to b, using Myers' algorithm
+// in linear space, and returns it with the unchanged lines.
+func diffLines(a, b []string) []diffOp {
+	d := &lineDiff{a: a, b: b, deleted: make([]bool, len(a)), inserted: make([]bool, len(b))}
+	d.compare(0, len(a), 0, len(b))
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && d.deleted[i]:
+			ops = append(ops, diffOp{'-', a[i], i, j})
+			i++
+		case j < len(b) && d.inserted[j]:
+			ops = append(ops, diffOp{'+', b[j], i, j})
+			j++
+		default:
+			ops = append(ops, diffOp{' ', a[i], i, j})
+			i++
+			j++
+		}
+	}
+	return ops
+}
+
+type lineDiff struct {
+	a, b              []string
+	deleted, inserted []bool
+}
+
+// compare marks the lines that differ between a[aLo:aHi] and b[bLo:bHi].
+func (d *lineDiff) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		aLo++
+		bLo++
+	}
+	for aLo < aHi && bLo < bHi && d.a[aHi-1] == d.b[bHi-1] {
+		aHi--
+		bHi--
+	}
+
+	x, y := -1, -1
+	if aLo < aHi && bLo < bHi {
+		x, y = middleSnake(d.a[aLo:aHi], d.b[bLo:bHi])
+	}
+	if x < 0 {
+		for i := aLo; i < aHi; i++ {
+			d.deleted[i] = true
+		}
+		for j := bLo; j < bHi; j++ {
+			d.inserted[j] = true
+		}
+		return
+	}
+	d.compare(aLo, aLo+x, bLo, bLo+y)
+	d.compare(aLo+x, aHi, bLo+y, bHi)
+}
+
+// middleSnake runs Myers' search from both ends of a and b at once and
+// returns the point where the two paths meet, which splits the problem in
+// two. It returns -1, -1 if a and b have nothing in common.
+func middleSnake(a, b []string) (int, int) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	off := maxD
+	fwd := make([]int, 2*maxD+2)
+	rev := make([]int, 2*maxD+2)
+	for i := range fwd {
+		fwd[i], rev[i] = -1, -1
+	}
+	fwd[off+1], rev[off+1] = 0, 0
+
+	delta := n - m
+	odd := delta%2 != 0
+	// Diagonals that have run off the edge of the grid need not be followed.
+	fStart, fEnd, rStart, rEnd := 0, 0, 0, 0
+
+	for d := 0; d < maxD; d++ {
+		for k := -d + fStart; k <= d-fEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && fwd[off+k-1] < fwd[off+k+1]) {
+				x = fwd[off+k+1]
+			} else {
+				x = fwd[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			fwd[off+k] = x
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case odd:
+				if r := off + delta - k; r >= 0 && r < len(rev) && rev[r] != -1 && x >= n-rev[r] {
+					return x, y
+				}
+			}
+		}
+		for k := -d + rStart; k <= d-rEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && rev[off+k-1] < rev[off+k+1]) {
+				x = rev[off+k+1]
+			} else {
+				x = rev[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			rev[off+k] = x
+			switch {
+			case x > n:
+				rEnd += 2
+			case y > m:
+				rStart += 2
+			case !odd:
+				if f := off + delta - k; f >= 0 && f < len(fwd) && fwd[f] != -1 {
+					fx := fwd[f]
+					if fx >= n-x {
+						return fx, fx - (f - off)
+					}
+				}
+			}
+		}
+	}
+	return -1, -1
+}
+
+var (
+	spaces  = regexp.MustCompile(`\s+`)
+	tagName = regexp.MustCompile(`^</?([A-Za-z][\w:-]*)`)
+)
+
+// voidElements never have end tags.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true, "input": true,
+	"link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// structure rewrites an HTML document as one tag, comment or run of text to
+// a line, indented by nesting depth, with runs of whitespace collapsed and
+// whitespace-only text dropped.
+func structure(doc string) string {
+	var out strings.Builder
+	depth := 0
+	emit := func(tok string) {
+		out.WriteString(strings.Repeat("  ", depth))
+		out.WriteString(tok)
+		out.WriteByte('\n')
+	}
+
+	for len(doc) > 0 {
+		if !strings.HasPrefix(doc, "<") {
+			n := strings.IndexByte(doc, '<')
+			if n < 0 {
+				n = len(doc)
+			}
+			if text := strings.TrimSpace(spaces.ReplaceAllString(doc[:n], " ")); text != "" {
+				emit(text)
+			}
+			doc = doc[n:]
+			continue
+		}
+
+		tok := doc[:tagEnd(doc)]
+		doc = doc[len(tok):]
+		if strings.HasPrefix(tok, "<!--") {
+			emit(tok)
+			continue
+		}
+		tok = spaces.ReplaceAllString(tok, " ")
+		m := tagName.FindStringSubmatch(tok)
+		if m == nil { // Doctype, processing instruction or stray "<"
+			emit(tok)
+			continue
+		}
+		name := strings.ToLower(m[1])
+		switch {
+		case strings.HasPrefix(tok, "</"):
+			if depth > 0 {
+				depth--
+			}
+			emit(tok)
+		case voidElements[name] || strings.HasSuffix(tok, "/>"):
+			emit(tok)
+		default:
+			emit(tok)
+			depth++
+			if name == "script" || name == "style" {
+				// Raw text: runs to the end tag, whatever it holds.
+				n := strings.Index(strings.ToLower(doc), "</"+name)
+				if n < 0 {
+					n = len(doc)
+				}
+				if text := strings.TrimSpace(doc[:n]); text != "" {
+					emit(text)
+				}
+				doc = doc[n:]
+			}
+		}
+	}
+	return out.String()
+}
+
+// tagEnd returns the length of the tag or comment doc starts with, skipping
+// any ">" inside quoted attribute values.
+func tagEnd(doc string) int {
+	if strings.HasPrefix(doc, "<!--") {
+		if n := strings.Index(doc[4:], "-->"); n >= 0 {
+			return n + 7
+		}
+		return len(doc)
+	}
+	var quote byte
+	for i := 1; i < len(doc); i++ {
+		switch c := doc[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i + 1
+		}
+	}
+	return len(doc)
+}
@@ -0,0 +1,118 @@
+package tidy
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func Test_UnifiedDiff(t *testing.T) {
+	from := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	to := "a\nb\nC\nd\ne\nf\ng\nh\ni\nj\nk\nl"
+	want := `--- in
++++ out
+@@ -1,6 +1,6 @@
+ a
+ b
+-c
++C
+ d
+ e
+ f
+@@ -9,3 +9,4 @@
+ i
+ j
+ k
++l
+\ No newline at end of file
+`
+	if got := UnifiedDiff("in", from, "out", to); got != want {
+		t.Errorf("UnifiedDiff() = \n%s\nwant\n%s", got, want)
+	}
+	if got := UnifiedDiff("in", from, "out", from); got != "" {
+		t.Errorf("Equal texts must give no diff, got\n%s", got)
+	}
+	if got := UnifiedDiff("in", "", "out", "x\n"); got != "--- in\n+++ out\n@@ -0,0 +1 @@\n+x\n" {
+		t.Errorf("Diff from empty = %q", got)
+	}
+}
+
+// The edit script must turn one text into the other, and be no longer than
+// needed.
+func Test_DiffLines(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func() []string {
+		lines := make([]string, r.Intn(40))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return lines
+	}
+	for i := 0; i < 500; i++ {
+		a, b := random(), random()
+		var gotA, gotB []string
+		edits := 0
+		for _, o := range diffLines(a, b) {
+			if o.op != '+' {
+				gotA = append(gotA, o.line)
+			}
+			if o.op != '-' {
+				gotB = append(gotB, o.line)
+			}
+			if o.op != ' ' {
+				edits++
+			}
+		}
+		if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+			t.Fatalf("diffLines(%q, %q) does not reproduce its inputs", a, b)
+		}
+		if want := len(a) + len(b) - 2*lcsLength(a, b); edits != want {
+			t.Fatalf("diffLines(%q, %q) made %d edits; want %d", a, b, edits, want)
+		}
+	}
+}
+
+func lcsLength(a, b []string) int {
+	l := make([][]int, len(a)+1)
+	for i := range l {
+		l[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				l[i][j] = l[i+1][j+1] + 1
+			case l[i+1][j] > l[i][j+1]:
+				l[i][j] = l[i+1][j]
+			default:
+				l[i][j] = l[i][j+1]
+			}
+		}
+	}
+	return l[0][0]
+}
+
+func Test_Structure(t *testing.T) {
+	a := "<html><body>\n<p class=\"x\"   id=y>Some\n   text <br>more</p><!-- note -->\n<script>if (a < b) {}</script></body></html>"
+	b := `<html>
+  <body>
+    <p class="x" id=y>
+      Some text
+      <br>
+      more
+    </p>
+    <!-- note -->
+    <script>
+      if (a < b) {}
+    </script>
+  </body>
+</html>
+`
+	if structure(a) != structure(b) {
+		t.Errorf("Reformatting must not change the structure:\n%s\n%s", structure(a), structure(b))
+	}
+	want := "<p>\n  <img src=\"a>b\">\n</p>\n"
+	if got := structure(`<p><img src="a>b"></p>`); got != want {
+		t.Errorf("structure() = %q; want %q", got, want)
+	}
+}
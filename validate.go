@@ -0,0 +1,84 @@
+package tidy
+
+/*
+#cgo CFLAGS: -I/usr/include/tidy
+#cgo LDFLAGS: -ltidy -L/usr/local/lib
+#include "tidy_compat.h"
+*/
+import "C"
+import (
+	"strings"
+)
+
+// ConfigWarning describes options whose combination contradicts itself or
+// has no effect. Options are named as libtidy names them; those libtidy
+// doesn't have, which GoTidy keeps itself, go by their setter's name, such as
+// "AutoDetectEncoding".
+type ConfigWarning struct {
+	Options []string // Names of the options involved.
+	Message string
+}
+
+func (w ConfigWarning) String() string {
+	return strings.Join(w.Options, ", ") + ": " + w.Message
+}
+
+// Validate checks the current options for combinations libtidy resolves
+// silently or ignores, and returns a warning for each one found. It returns
+// nil if there is nothing to report.
+func (this *Tidy) Validate() []ConfigWarning {
+	var warnings []ConfigWarning
+	warn := func(msg string, opts ...string) {
+		warnings = append(warnings, ConfigWarning{opts, msg})
+	}
+
+	xml := this.optGetInt(C.TidyXmlOut) != 0
+	xhtml := this.optGetInt(C.TidyXhtmlOut) != 0
+	html := this.optGetInt(C.TidyHtmlOut) != 0
+	inputXml := this.optGetInt(C.TidyXmlTags) != 0
+
+	switch {
+	case xml && xhtml:
+		warn("XML and XHTML output both requested; XHTML is written", "output-xml", "output-xhtml")
+	case html && (xml || xhtml):
+		warn("HTML output requested along with XML or XHTML; HTML is ignored", "output-html", "output-xml", "output-xhtml")
+	}
+
+	if AutoBool(this.optGetInt(C.TidyBodyOnly)) != False {
+		if inputXml {
+			warn("show-body-only has no effect on XML input", "show-body-only", "input-xml")
+		} else if xml && !xhtml {
+			warn("show-body-only has no effect with XML output; use output-xhtml for a well-formed fragment", "show-body-only", "output-xml")
+		}
+	}
+
+	if this.optGetInt(C.TidyXmlDecl) != 0 && !xml && !xhtml && !inputXml {
+		warn("An XML declaration is only added to XML or XHTML output", "add-xml-decl")
+	}
+
+	in := Encoding(this.optGetInt(C.TidyInCharEncoding))
+	out := Encoding(this.optGetInt(C.TidyOutCharEncoding))
+	if in != out && (passedThrough(in) || passedThrough(out)) {
+		warn("libtidy can't convert from "+in.String()+" to "+out.String()+"; the output will be mislabelled or garbled", "input-encoding", "output-encoding")
+	}
+
+	if this.optGetInt(C.TidyKeepFileTimes) != 0 && this.optGetInt(C.TidyWriteBack) == 0 {
+		warn("keep-time only applies when tidying a file in place", "keep-time", "write-back")
+	}
+
+	if this.extra.contentType != "" && !this.extra.detectEncoding {
+		warn("The Content-Type is only used to detect the input encoding", "ContentType", "AutoDetectEncoding")
+	}
+
+	return warnings
+}
+
+// passedThrough reports whether libtidy hands text in e through as bytes
+// rather than decoding it, so that it can't convert it to another encoding.
+func passedThrough(e Encoding) bool {
+	switch e {
+	case Raw, Iso2022, Big5, Shiftjis:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package tidy
+
+/*
+#cgo CFLAGS: -I/usr/include/tidy
+#cgo LDFLAGS: -ltidy -L/usr/local/lib -ldl
+#include "tidy_compat.h"
+*/
+import "C"
+
+// Library identifies which flavour of libtidy GoTidy is linked against.
+type Library int
+
+const (
+	Tidyp Library = iota
+	ClassicTidy
+	TidyHtml5
+)
+
+func (l Library) String() string {
+	switch l {
+	case Tidyp:
+		return "tidyp"
+	case ClassicTidy:
+		return "tidy"
+	case TidyHtml5:
+		return "tidy-html5"
+	}
+	return "unknown"
+}
+
+// Capabilities describes the linked libtidy and the optional features it has.
+type Capabilities struct {
+	Library     Library
+	Version     string // Empty unless Library is TidyHtml5.
+	ReleaseDate string
+
+	SortAttributes bool // SortAttributes can be set.
+	AnchorAsName   bool // AnchorAsName can be set.
+	Html5Tags      bool // <section>, <nav> and friends are known elements.
+	MessageCodes   bool // Diagnostics carry stable message keys.
+}
+
+// LibraryVersion returns the semantic version of the linked libtidy, e.g.
+// "5.6.0". Only tidy-html5 reports a version; for classic libtidy and tidyp
+// the result is empty and ReleaseDate is the best there is.
+func LibraryVersion() string {
+	v := C.gotidyLibraryVersion()
+	if v == nil {
+		return ""
+	}
+	return C.GoString((*C.char)(v))
+}
+
+// ReleaseDate returns the release date string of the linked libtidy, e.g.
+// "25 March 2009".
+func ReleaseDate() string {
+	return C.GoString((*C.char)(C.tidyReleaseDate()))
+}
+
+// LibraryCapabilities reports which libtidy is loaded and what it supports.
+// It asks the library itself, by its version and by looking options up by
+// name, so the answer holds even if the library was upgraded after GoTidy
+// was built.
+func LibraryCapabilities() Capabilities {
+	c := Capabilities{
+		Version:     LibraryVersion(),
+		ReleaseDate: ReleaseDate(),
+	}
+	has := func(name string) bool {
+		_, err := optionId(name)
+		return err == nil
+	}
+	c.SortAttributes = has("sort-attributes")
+	c.AnchorAsName = has("anchor-as-name")
+
+	switch {
+	case c.Version != "" || has("drop-empty-elements") || C.gotidyIsHtml5() != 0:
+		// Only tidy-html5 reports a version or has HTML5 options; the
+		// build tag is the fallback.
+		c.Library = TidyHtml5
+		c.Html5Tags = true
+		// Message codes need the library's mute support and a build
+		// that installs the message callback.
+		c.MessageCodes = messageCodes && has("mute")
+	case c.AnchorAsName:
+		c.Library = ClassicTidy
+	default:
+		// tidyp forked before anchor-as-name was added.
+		c.Library = Tidyp
+	}
+	return c
+}